@@ -0,0 +1,126 @@
+package main
+
+import (
+	"log/slog"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+var (
+	announceMutex sync.Mutex
+	announcing    *exec.Cmd
+)
+
+// applyScreenReader draws borders with spaces in screen-reader mode, so a
+// screen reader following the cursor doesn't read out box-drawing
+// characters.
+func applyScreenReader() {
+	if !config.ScreenReader {
+		return
+	}
+	b := &tview.Borders
+	for _, r := range []*rune{
+		&b.Horizontal, &b.Vertical, &b.TopLeft, &b.TopRight, &b.BottomLeft, &b.BottomRight,
+		&b.LeftT, &b.RightT, &b.TopT, &b.BottomT, &b.Cross,
+		&b.HorizontalFocus, &b.VerticalFocus, &b.TopLeftFocus, &b.TopRightFocus, &b.BottomLeftFocus, &b.BottomRightFocus,
+	} {
+		*r = ' '
+	}
+}
+
+// placeCursor puts the terminal cursor at the start of the selected row, of
+// the feed list or the items, which is where screen readers look for the
+// line to read.
+func (u *UI) placeCursor(screen tcell.Screen) {
+	// The application is locked while drawing, so focus is asked of the
+	// tables themselves.
+	table := u.table
+	if u.feeds.HasFocus() {
+		table = u.feeds
+	} else if !u.table.HasFocus() {
+		return
+	}
+	row, _ := table.GetSelection()
+	offset, _ := table.GetOffset()
+	x, y, _, height := table.GetInnerRect()
+	if row < offset || row-offset >= height {
+		return
+	}
+	screen.ShowCursor(x, y+row-offset)
+}
+
+// itemAnnouncement describes an item in words, for announce-command: its
+// title, feed, date and read and starred state.
+func (u *UI) itemAnnouncement(index int) string {
+	item := u.items[index]
+	parts := []string{CleanString(item.Title), CleanString(item.FeedTitle), formatDate(item.Date, u.now)}
+	if item.Read {
+		parts = append(parts, "read")
+	} else {
+		parts = append(parts, "unread")
+	}
+	if item.Starred {
+		parts = append(parts, "starred")
+	}
+	if item.Duration > 0 {
+		parts = append(parts, formatDuration(item.Duration))
+	}
+	return strings.Join(parts, ". ")
+}
+
+// announceSelection announces the selected row in screen-reader mode.
+func (u *UI) announceSelection() {
+	if !config.ScreenReader {
+		return
+	}
+	row, _ := u.table.GetSelection()
+	if header, ok := u.headers[row]; ok {
+		announce(header.label)
+		return
+	}
+	if index := u.selected(); index >= 0 {
+		announce(u.itemAnnouncement(index))
+	}
+}
+
+// announce gives text to announce-command on stdin, stopping the previous
+// announcement so that moving quickly doesn't queue them up.
+func announce(text string) {
+	if config.AnnounceCommand == "" {
+		return
+	}
+	announceMutex.Lock()
+	defer announceMutex.Unlock()
+	if announcing != nil {
+		announcing.Process.Kill()
+	}
+	cmd := shellCommand(config.AnnounceCommand)
+	cmd.Stdin = strings.NewReader(text + "\n")
+	if err := cmd.Start(); err != nil {
+		slog.Error("error running announce-command", "err", err)
+		announcing = nil
+		return
+	}
+	announcing = cmd
+	goSafe(func() {
+		cmd.Wait()
+	})
+}
+
+// groupMarker shows whether a feed group is collapsed: a triangle, or the
+// word in screen-reader mode.
+func groupMarker(collapsed bool) string {
+	switch {
+	case config.ScreenReader && collapsed:
+		return "collapsed,"
+	case config.ScreenReader:
+		return "expanded,"
+	case collapsed:
+		return "▸"
+	}
+	return "▾"
+}
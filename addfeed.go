@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mmcdole/gofeed"
+	"github.com/rivo/tview"
+)
+
+// previewItems is how many of a candidate feed's items are listed.
+const previewItems = 15
+
+// What becomes of the items a feed already has when subscribing to it.
+const (
+	backlogUnread = iota
+	backlogRead
+	backlogHidden
+)
+
+var backlogChoices = []string{"Unread", "Read", "Hidden"}
+
+// showAddFeed asks for a feed's URL, name and category and what to do with
+// the items it already has, then fetches it and shows a preview to confirm
+// before subscribing.
+func (u *UI) showAddFeed() {
+	if u.backend != nil {
+		u.showMessage("Feeds are managed on " + config.BackendURL + "; subscribe there.")
+		return
+	}
+
+	status := tview.NewTextView().SetDynamicColors(true)
+	status.SetBackgroundColor(tcell.ColorDefault)
+	form := tview.NewForm().
+		AddInputField("URL", "", 0, nil, nil).
+		AddInputField("Name", "", 0, nil, nil).
+		AddInputField("Category", "", 0, nil, nil).
+		AddDropDown("Existing items", backlogChoices, backlogUnread, nil)
+	form.SetBackgroundColor(tcell.ColorDefault)
+
+	form.AddButton("Preview", func() {
+		source := FeedSource{
+			URL:      strings.TrimSpace(form.GetFormItemByLabel("URL").(*tview.InputField).GetText()),
+			Name:     strings.TrimSpace(form.GetFormItemByLabel("Name").(*tview.InputField).GetText()),
+			Category: strings.TrimSpace(form.GetFormItemByLabel("Category").(*tview.InputField).GetText()),
+		}
+		backlog, _ := form.GetFormItemByLabel("Existing items").(*tview.DropDown).GetCurrentOption()
+		if source.URL == "" {
+			status.SetText("[red]Enter the feed's URL")
+			return
+		}
+		status.SetText("Fetching " + tview.Escape(source.URL) + "...")
+		goSafe(func() {
+			feed, _, err := fetchFeed(context.Background(), gofeed.NewParser(), source)
+			u.app.QueueUpdateDraw(func() {
+				if err != nil {
+					status.SetText("[red]" + tview.Escape(err.Error()))
+					return
+				}
+				u.pages.RemovePage("add-feed")
+				u.showFeedPreview(source, feed, backlog)
+			})
+		})
+	})
+	form.AddButton("Cancel", func() { u.pages.RemovePage("add-feed") })
+	form.SetCancelFunc(func() { u.pages.RemovePage("add-feed") })
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(form, 0, 1, true).
+		AddItem(status, 1, 0, false)
+	layout.SetBorder(true).SetTitle(" Add feed ").SetBorderPadding(0, 0, 1, 1)
+	layout.SetBackgroundColor(tcell.ColorDefault)
+	u.pages.AddPage("add-feed", centered(layout, 80, 13), true, true)
+}
+
+// showFeedPreview lists a candidate feed's recent items; s subscribes and
+// Esc discards it. Feeds already subscribed under their own or their
+// declared self URL can't be added again.
+func (u *UI) showFeedPreview(source FeedSource, feed *gofeed.Feed, backlog int) {
+	items := feedItems(source, feed)
+	existing, _ := getFeedSources()
+	subscribed, isSubscribed := findSubscription(existing, source.URL, feed.FeedLink)
+
+	view := tview.NewTextView().SetDynamicColors(true).SetWordWrap(true)
+	view.SetBorder(true).SetTitle(" Preview (s to subscribe, Esc to cancel) ").SetBorderPadding(0, 0, 1, 1)
+	view.SetBackgroundColor(tcell.ColorDefault)
+	text := feedPreviewText(feed, items, u.now)
+	if isSubscribed {
+		text = fmt.Sprintf("[red]Already subscribed as %s[-]\n\n", tview.Escape(sourceLabel(subscribed))) + text
+	}
+	view.SetText(text)
+
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape || event.Rune() == 'q':
+			u.pages.RemovePage("feed-preview")
+			return nil
+		case event.Rune() == 's' && !isSubscribed:
+			u.pages.RemovePage("feed-preview")
+			u.subscribe(source, items, backlog)
+			return nil
+		}
+		return event
+	})
+	u.pages.AddPage("feed-preview", centered(view, 100, 30), true, true)
+}
+
+// feedPreviewText describes a feed and lists its newest items, noting
+// whether they carry the full text or only summaries.
+func feedPreviewText(feed *gofeed.Feed, items []FeedItem, now time.Time) string {
+	theme := currentTheme()
+	var sb strings.Builder
+	sb.WriteString(theme.feed(tview.Escape(CleanString(feed.Title))) + "\n")
+	if feed.Link != "" {
+		sb.WriteString(theme.dim(tview.Escape(stripControl(feed.Link))) + "\n")
+	}
+	if description := htmlToText(feed.Description); description != "" {
+		sb.WriteString("\n" + tview.Escape(stripControl(description)) + "\n")
+	}
+
+	length := 0
+	for _, item := range items {
+		length += len(htmlToText(item.Description.String()))
+	}
+	content := "no text"
+	if len(items) > 0 && length/len(items) > 1000 {
+		content = "full text"
+	} else if length > 0 {
+		content = "summaries"
+	}
+	fmt.Fprintf(&sb, "\n%d items with %s\n\n", len(items), content)
+
+	sorted := make([]int, len(items))
+	for i := range sorted {
+		sorted[i] = i
+	}
+	sortItems(sorted, items, sortNewest, lessName)
+	for n, i := range sorted {
+		if n == previewItems {
+			break
+		}
+		fmt.Fprintf(&sb, "%s  %s\n", tview.Escape(CleanString(items[i].Title)), theme.dim(formatDate(items[i].Date, now)))
+	}
+	return sb.String()
+}
+
+// subscribe appends a feed to feeds.csv and shows its items: unread, read,
+// or not at all for a hidden backlog, which the feed's since option keeps
+// out of later fetches too.
+func (u *UI) subscribe(source FeedSource, items []FeedItem, backlog int) {
+	if backlog == backlogHidden {
+		source.Since = time.Now().UTC()
+	}
+	added, err := appendFeedSources([]FeedSource{source})
+	if err != nil {
+		u.showMessage(err.Error())
+		return
+	}
+	if added == 0 {
+		u.showMessage("Already subscribed to " + source.URL)
+		return
+	}
+	// So refreshes fetch it from now on
+	if sources, err := getFeedSources(); err != nil {
+		slog.Error("error reloading feeds.csv", "err", err)
+		u.sources = append(u.sources, source)
+	} else {
+		u.sources = sources
+	}
+	if backlog == backlogUnread {
+		u.addItems(items)
+		return
+	}
+
+	// Items without a date look new at every fetch, so they are marked
+	// read even when hidden.
+	for i := range items {
+		items[i].Read = true
+	}
+	if u.state != nil {
+		state := u.state
+		goWrite(func() {
+			if err := state.updateAll(items); err != nil {
+				slog.Error("error saving state file", "err", err)
+			}
+		})
+	}
+	if backlog == backlogRead {
+		u.addItems(items)
+	}
+}
+
+// showMessage shows text in a dialog closed with Enter or Esc.
+func (u *UI) showMessage(text string) {
+	modal := tview.NewModal().
+		SetText(tview.Escape(text)).
+		AddButtons([]string{"OK"}).
+		SetDoneFunc(func(int, string) { u.pages.RemovePage("message") })
+	u.pages.AddPage("message", modal, true, true)
+}
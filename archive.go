@@ -0,0 +1,459 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// maxArchiveAsset and maxArchiveAssets limit the images and stylesheets
+	// inlined into one snapshot; ones past them are left linked.
+	maxArchiveAsset  = 5 << 20
+	maxArchiveAssets = 40 << 20
+)
+
+// archived is an article saved by archiveItem.
+type archived struct {
+	Path      string    `json:"path"`
+	URL       string    `json:"url"`
+	Title     string    `json:"title"`
+	FeedTitle string    `json:"feed_title"`
+	Time      time.Time `json:"time"`
+}
+
+// archiveMutex guards the archive's index.json.
+var archiveMutex sync.Mutex
+
+// archiveDir returns archive-dir from the config, or the archive directory
+// under the data directory.
+func archiveDir() (string, error) {
+	if config.ArchiveDir != "" {
+		return config.ArchiveDir, nil
+	}
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "archive"), nil
+}
+
+// loadArchive reads the archive's index, dropping snapshots that were
+// deleted. The caller holds archiveMutex.
+func loadArchive() []archived {
+	dir, err := archiveDir()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "index.json"))
+	if err != nil {
+		return nil
+	}
+	var all []archived
+	if err := json.Unmarshal(data, &all); err != nil {
+		slog.Error("error reading archive index", "dir", dir, "err", err)
+		return nil
+	}
+	var existing []archived
+	for _, a := range all {
+		if _, err := os.Stat(a.Path); err == nil {
+			existing = append(existing, a)
+		}
+	}
+	return existing
+}
+
+// saveArchive writes the archive's index. The caller holds archiveMutex.
+func saveArchive(entries []archived) error {
+	dir, err := archiveDir()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, "index.json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// isArchived reports whether the page at url is in the archive.
+func isArchived(url string) bool {
+	archiveMutex.Lock()
+	defer archiveMutex.Unlock()
+	for _, a := range loadArchive() {
+		if a.URL == url {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveItem saves a self-contained snapshot of an item's page, with its
+// images and stylesheets inlined and its scripts removed, and returns the
+// snapshot's path.
+func archiveItem(item FeedItem) (string, error) {
+	if item.Link == "" {
+		return "", fmt.Errorf("the item has no link")
+	}
+	base, err := neturl.Parse(item.Link)
+	if err != nil {
+		return "", err
+	}
+	body, err := articleCrawler.fetch(item.Link)
+	if err != nil {
+		return "", fmt.Errorf("error fetching %s: %v", item.Link, err)
+	}
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("error parsing %s: %v", item.Link, err)
+	}
+	if root, err := articleRoot(body); err == nil {
+		indexArticle(item.Link, nodeText(root), true)
+		if err := saveFullText(); err != nil {
+			slog.Warn("error saving full-text index", "err", err)
+		}
+	}
+	budget := maxArchiveAssets
+	inlineAssets(doc, base, &budget)
+	markSnapshot(doc, item)
+
+	dir, err := archiveDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("error creating archive directory: %v", err)
+	}
+	name := time.Now().Format("2006-01-02") + " " + archiveName(CleanString(item.Title)) + ".html"
+	file, path, err := createUnique(dir, name)
+	if err != nil {
+		return "", err
+	}
+	if err := html.Render(file, doc); err != nil {
+		file.Close()
+		os.Remove(path)
+		return "", fmt.Errorf("error writing %s: %v", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	archiveMutex.Lock()
+	defer archiveMutex.Unlock()
+	entries := append(loadArchive(), archived{
+		Path:      path,
+		URL:       item.Link,
+		Title:     item.Title,
+		FeedTitle: item.FeedTitle,
+		Time:      time.Now().UTC(),
+	})
+	if err := saveArchive(entries); err != nil {
+		return path, fmt.Errorf("error saving archive index: %v", err)
+	}
+	slog.Info("archived article", "url", item.Link, "path", path)
+	return path, nil
+}
+
+// archiveName makes a title usable as a file name.
+func archiveName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		case r < ' ':
+			return -1
+		}
+		return r
+	}, title)
+	if runes := []rune(strings.TrimSpace(name)); len(runes) > 80 {
+		name = string(runes[:80])
+	}
+	if strings.TrimSpace(name) == "" {
+		return "article"
+	}
+	return strings.TrimSpace(name)
+}
+
+// inlineAssets makes a page self-contained: scripts and frames go, images
+// and stylesheets are embedded as long as budget allows, and links are made
+// absolute.
+func inlineAssets(n *html.Node, base *neturl.URL, budget *int) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode {
+			switch c.DataAtom {
+			case atom.Script, atom.Noscript, atom.Iframe, atom.Object, atom.Embed:
+				n.RemoveChild(c)
+				c = next
+				continue
+			case atom.Link:
+				if hasRel(nodeAttr(c, "rel"), "stylesheet") {
+					if css, ok := fetchAsset(resolveRef(base, nodeAttr(c, "href")), budget); ok {
+						style := &html.Node{Type: html.ElementNode, Data: "style", DataAtom: atom.Style}
+						style.AppendChild(&html.Node{Type: html.TextNode, Data: string(css.data)})
+						n.InsertBefore(style, c)
+						n.RemoveChild(c)
+						c = next
+						continue
+					}
+				}
+			}
+			rewriteAttrs(c, base, budget)
+		}
+		inlineAssets(c, base, budget)
+		c = next
+	}
+}
+
+// rewriteAttrs embeds an element's image and makes its links absolute,
+// dropping event handlers.
+func rewriteAttrs(n *html.Node, base *neturl.URL, budget *int) {
+	var kept []html.Attribute
+	for _, a := range n.Attr {
+		key := strings.ToLower(a.Key)
+		switch {
+		case strings.HasPrefix(key, "on"):
+			continue
+		case key == "srcset" && n.DataAtom == atom.Img:
+			// The embedded src is used instead
+			continue
+		case key == "src" && n.DataAtom == atom.Img:
+			if asset, ok := fetchAsset(resolveRef(base, a.Val), budget); ok {
+				a.Val = "data:" + asset.mimeType + ";base64," + base64.StdEncoding.EncodeToString(asset.data)
+			} else {
+				a.Val = resolveRef(base, a.Val)
+			}
+		case key == "href" || key == "src" || key == "poster":
+			if !strings.HasPrefix(a.Val, "#") {
+				a.Val = resolveRef(base, a.Val)
+			}
+		}
+		kept = append(kept, a)
+	}
+	n.Attr = kept
+}
+
+// markSnapshot notes in the page's head where and when it was archived.
+func markSnapshot(doc *html.Node, item FeedItem) {
+	head := findElement(doc, "head")
+	if head == nil {
+		return
+	}
+	comment := &html.Node{Type: html.CommentNode,
+		Data: fmt.Sprintf(" Archived by newseum from %s on %s ", item.Link, time.Now().UTC().Format(time.RFC3339))}
+	meta := &html.Node{Type: html.ElementNode, Data: "meta", DataAtom: atom.Meta, Attr: []html.Attribute{
+		{Key: "charset", Val: "utf-8"},
+	}}
+	head.InsertBefore(meta, head.FirstChild)
+	head.InsertBefore(comment, meta)
+}
+
+func nodeAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func resolveRef(base *neturl.URL, ref string) string {
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+type archiveAsset struct {
+	data     []byte
+	mimeType string
+}
+
+var cssURL = regexp.MustCompile(`url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+
+// fetchAsset downloads an image or stylesheet to embed, if it fits in the
+// budget. Stylesheets get their own images embedded and links resolved.
+func fetchAsset(rawURL string, budget *int) (archiveAsset, bool) {
+	if *budget <= 0 || strings.HasPrefix(rawURL, "data:") ||
+		!(strings.HasPrefix(rawURL, "http://") || strings.HasPrefix(rawURL, "https://")) {
+		return archiveAsset{}, false
+	}
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return archiveAsset{}, false
+	}
+	req.Header.Set("User-Agent", "newseum")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		slog.Warn("error fetching page asset", "url", rawURL, "err", err)
+		return archiveAsset{}, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		slog.Warn("error fetching page asset", "url", rawURL, "status", resp.Status)
+		return archiveAsset{}, false
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxArchiveAsset+1))
+	if err != nil || len(data) > maxArchiveAsset || len(data) > *budget {
+		return archiveAsset{}, false
+	}
+	*budget -= len(data)
+
+	mimeType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	if mimeType == "text/css" {
+		base, _ := neturl.Parse(rawURL)
+		data = cssURL.ReplaceAllFunc(data, func(match []byte) []byte {
+			ref := string(cssURL.FindSubmatch(match)[1])
+			if strings.HasPrefix(ref, "data:") {
+				return match
+			}
+			target := resolveRef(base, ref)
+			if asset, ok := fetchAsset(target, budget); ok && strings.HasPrefix(asset.mimeType, "image/") {
+				target = "data:" + asset.mimeType + ";base64," + base64.StdEncoding.EncodeToString(asset.data)
+			}
+			return []byte(`url("` + target + `")`)
+		})
+	}
+	return archiveAsset{data, mimeType}, true
+}
+
+// archiveSelected archives the selected item's page in the background.
+func (u *UI) archiveSelected() {
+	index := u.selected()
+	if index < 0 {
+		return
+	}
+	item := u.items[index]
+	if item.Link == "" {
+		u.setStatus("This item has no link to archive")
+		return
+	}
+	u.setStatus("Archiving " + tview.Escape(CleanString(item.Title)) + "...")
+	goSafe(func() {
+		path, err := archiveItem(item)
+		u.app.QueueUpdateDraw(func() {
+			if err != nil {
+				slog.Error("error archiving article", "url", item.Link, "err", err)
+				u.setStatus("[red]Error archiving: " + tview.Escape(err.Error()))
+				return
+			}
+			u.setStatus("Archived to " + tview.Escape(path) + " (I to list the archive)")
+		})
+	})
+}
+
+// archiveStarred archives a newly starred item's page with archive-starred,
+// unless it is archived already.
+func archiveStarred(item FeedItem) {
+	if !config.ArchiveStarred || !item.Starred || item.Link == "" {
+		return
+	}
+	goSafe(func() {
+		if isArchived(item.Link) {
+			return
+		}
+		if _, err := archiveItem(item); err != nil {
+			slog.Error("error archiving starred article", "url", item.Link, "err", err)
+		}
+	})
+}
+
+// showArchive lists the archived articles, newest first. Enter opens one
+// and x deletes it.
+func (u *UI) showArchive() {
+	archiveMutex.Lock()
+	entries := loadArchive()
+	archiveMutex.Unlock()
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+
+	list := tview.NewList().ShowSecondaryText(true)
+	list.SetBorder(true).SetTitle(" Archive (Enter to open, x to delete, Esc to close) ")
+	list.SetBackgroundColor(tcell.ColorDefault)
+	if len(entries) == 0 {
+		list.AddItem("Nothing archived yet; press K on an item to archive its page", "", 0, nil)
+	}
+	for _, a := range entries {
+		list.AddItem(tview.Escape(CleanString(a.FeedTitle)+" — "+CleanString(a.Title)),
+			tview.Escape("archived "+formatDate(a.Time, u.now)+" · "+a.URL), 0, nil)
+	}
+
+	list.SetSelectedFunc(func(i int, _, _ string, _ rune) {
+		if i >= len(entries) {
+			return
+		}
+		path := entries[i].Path
+		if err := openBrowser(path); err != nil {
+			slog.Error("error opening archived article", "path", path, "err", err)
+			u.setStatus("[red]Error opening " + tview.Escape(filepath.Base(path)))
+		}
+	})
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape || event.Rune() == 'q':
+			u.pages.RemovePage("archive")
+			return nil
+		case event.Rune() == 'x' && list.GetCurrentItem() < len(entries):
+			u.confirmDeleteArchived(entries[list.GetCurrentItem()].Path)
+			return nil
+		}
+		return event
+	})
+	u.pages.AddPage("archive", centered(list, 120, 24), true, true)
+}
+
+// confirmDeleteArchived asks before deleting an archived article.
+func (u *UI) confirmDeleteArchived(path string) {
+	modal := tview.NewModal().
+		SetText("Delete " + tview.Escape(filepath.Base(path)) + " from the archive?").
+		AddButtons([]string{"Delete", "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			u.pages.RemovePage("delete-archived")
+			if label != "Delete" {
+				return
+			}
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				slog.Error("error deleting archived article", "path", path, "err", err)
+				u.setStatus("[red]Error deleting " + tview.Escape(filepath.Base(path)))
+				return
+			}
+			archiveMutex.Lock()
+			err := saveArchive(loadArchive())
+			archiveMutex.Unlock()
+			if err != nil {
+				slog.Error("error saving archive index", "err", err)
+			}
+			u.pages.RemovePage("archive")
+			u.showArchive()
+			u.setStatus("Deleted " + tview.Escape(filepath.Base(path)))
+		})
+	u.pages.AddPage("delete-archived", modal, true, true)
+}
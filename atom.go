@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"log/slog"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"time"
+)
+
+// atomItems is how many items /feed.atom has unless limit= says otherwise,
+// and maxAtomItems the most it can ask for.
+const (
+	atomItems    = 100
+	maxAtomItems = 1000
+)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Title     string        `xml:"title"`
+	ID        string        `xml:"id"`
+	Updated   string        `xml:"updated"`
+	Published string        `xml:"published,omitempty"`
+	Links     []atomLink    `xml:"link"`
+	Author    *atomAuthor   `xml:"author,omitempty"`
+	Category  *atomCategory `xml:"category,omitempty"`
+	Summary   *atomText     `xml:"summary,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// serveAtomFeed republishes the merged timeline as one Atom feed for other
+// readers and devices. unread=1 keeps the unread items, q= those matching a
+// search and category= those of one category; limit= caps how many there
+// are.
+func serveAtomFeed(w http.ResponseWriter, r *http.Request) {
+	items, _, err := savedItems()
+	if err != nil {
+		slog.Error("error loading read state", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := atomItems
+	if n, err := strconv.Atoi(query.Get("limit")); err == nil && n > 0 {
+		limit = min(n, maxAtomItems)
+	}
+	unread := query.Get("unread") != ""
+	search := query.Get("q")
+	category := query.Get("category")
+
+	self := &neturl.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	if r.TLS != nil {
+		self.Scheme = "https"
+	}
+	feed := atomFeed{
+		Title: "newseum",
+		ID:    self.String(),
+		Links: []atomLink{{Rel: "self", Type: "application/atom+xml", Href: self.String()}},
+	}
+	var updated time.Time
+	for _, item := range items {
+		if len(feed.Entries) == limit {
+			break
+		}
+		if unread && item.Read || category != "" && item.Category != category || search != "" && !matchesQuery(item, search) {
+			continue
+		}
+		feed.Entries = append(feed.Entries, atomItemEntry(item))
+		updated = latest(updated, item.Date, item.UpdatedAt)
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(feed); err != nil {
+		slog.Error("error writing Atom feed", "err", err)
+	}
+}
+
+// atomItemEntry is an item as an Atom entry, credited to its feed.
+func atomItemEntry(item FeedItem) atomEntry {
+	date := item.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	entry := atomEntry{
+		Title:     CleanString(item.Title),
+		ID:        atomID(itemKey(item)),
+		Updated:   latest(date, item.UpdatedAt).UTC().Format(time.RFC3339),
+		Published: date.UTC().Format(time.RFC3339),
+	}
+	if item.Link != "" {
+		entry.Links = append(entry.Links, atomLink{Rel: "alternate", Href: item.Link})
+	}
+	for _, enclosure := range item.Enclosures {
+		entry.Links = append(entry.Links, atomLink{Rel: "enclosure", Type: enclosure.Type, Href: enclosure.URL})
+	}
+	if item.FeedTitle != "" {
+		entry.Author = &atomAuthor{Name: CleanString(item.FeedTitle)}
+	}
+	if item.Category != "" {
+		entry.Category = &atomCategory{Term: item.Category}
+	}
+	if !item.Description.empty() {
+		entry.Summary = &atomText{Type: "html", Body: item.Description.String()}
+	}
+	return entry
+}
+
+// atomID makes an item key into an Atom ID, which must be an IRI: links are
+// used as they are and other keys, such as GUIDs, hashed into a URN.
+func atomID(key string) string {
+	if u, err := neturl.Parse(key); err == nil && u.IsAbs() {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "urn:newseum:" + hex.EncodeToString(sum[:16])
+}
+
+// latest returns the latest of the times.
+func latest(times ...time.Time) time.Time {
+	var t time.Time
+	for _, other := range times {
+		if other.After(t) {
+			t = other
+		}
+	}
+	return t
+}
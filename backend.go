@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Backend is a remote aggregator that newseum reads items from instead of
+// fetching feeds.csv itself. Read and starred state is kept on the server.
+type Backend interface {
+	// Fetch returns the items to show, newest first. Cancelling ctx aborts
+	// the requests in flight.
+	Fetch(ctx context.Context) ([]FeedItem, error)
+	MarkRead(item FeedItem, read bool) error
+	SetStarred(item FeedItem, starred bool) error
+}
+
+// newBackend returns the backend selected by the config, or nil when feeds
+// are fetched directly.
+func newBackend(cfg Config) (Backend, error) {
+	switch cfg.Backend {
+	case "":
+		return nil, nil
+	case "greader":
+		return newGReaderBackend(cfg.BackendURL, cfg.BackendUser, cfg.BackendPassword)
+	case "ttrss":
+		return newTTRSSBackend(cfg.BackendURL, cfg.BackendUser, cfg.BackendPassword)
+	case "nextcloud":
+		return newNextcloudBackend(cfg.BackendURL, cfg.BackendUser, cfg.BackendPassword)
+	default:
+		return nil, fmt.Errorf("unknown backend %q", cfg.Backend)
+	}
+}
+
+// limitPerFeed keeps the first max items of each feed in items, which are
+// newest first, applying max-items to what a backend returns. Feeds are
+// told apart by URL, or by title for backends that don't give it. 0 keeps
+// them all.
+func limitPerFeed(items []FeedItem, max int) []FeedItem {
+	if max <= 0 {
+		return items
+	}
+	counts := make(map[string]int)
+	kept := items[:0]
+	for _, item := range items {
+		feed := item.FeedURL
+		if feed == "" {
+			feed = item.FeedTitle
+		}
+		counts[feed]++
+		if counts[feed] <= max {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
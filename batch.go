@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// defaultOpenUnread is how many items O offers to open without open-unread
+// in the config.
+const defaultOpenUnread = 10
+
+// showOpenUnread asks how many of the current tab's newest unread items to
+// open in the browser.
+func (u *UI) showOpenUnread() {
+	count := config.OpenUnread
+	if count == 0 {
+		count = defaultOpenUnread
+	}
+
+	input := tview.NewInputField().
+		SetLabel("Open how many unread items? ").
+		SetText(strconv.Itoa(count)).
+		SetAcceptanceFunc(tview.InputFieldInteger)
+	input.SetBorder(true).SetTitle(" Open unread (Enter to open, Esc to cancel) ").SetBorderPadding(0, 0, 1, 1)
+	input.SetBackgroundColor(tcell.ColorDefault)
+	input.SetDoneFunc(func(key tcell.Key) {
+		u.pages.RemovePage("open-unread")
+		if key != tcell.KeyEnter {
+			return
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(input.GetText()))
+		if err != nil || n <= 0 {
+			return
+		}
+		u.openUnread(n)
+	})
+	u.pages.AddPage("open-unread", centered(input, 60, 3), true, true)
+}
+
+// openUnread opens the newest n unread items of the current tab in browser
+// tabs and marks them read.
+func (u *UI) openUnread(n int) {
+	var unread []int
+	for _, i := range u.visible {
+		if i >= 0 && !u.items[i].Read && u.items[i].Link != "" {
+			unread = append(unread, i)
+		}
+	}
+	sort.SliceStable(unread, func(a, b int) bool {
+		return u.items[unread[a]].Date.After(u.items[unread[b]].Date)
+	})
+	if len(unread) > n {
+		unread = unread[:n]
+	}
+
+	opened := 0
+	for _, i := range unread {
+		err := checkURL(u.items[i].Link)
+		if err == nil {
+			err = openBrowser(u.items[i].Link)
+		}
+		if errors.Is(err, errNoBrowser) {
+			u.setStatus("No browser in this session; set opener or terminal-browser")
+			return
+		}
+		if err != nil {
+			slog.Error("error opening item", "url", u.items[i].Link, "err", err)
+			continue
+		}
+		u.markRead(i, true)
+		u.recordOpened(u.items[i])
+		opened++
+	}
+	u.setStatus("Opened " + plural(opened, "item"))
+}
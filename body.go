@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"io"
+	"log/slog"
+)
+
+// minDeflatedBody is the length from which item bodies are kept deflated;
+// shorter ones don't shrink enough to be worth it.
+const minDeflatedBody = 512
+
+// itemBody is an item's HTML description. Descriptions take most of the
+// memory of thousands of items, so long ones are kept deflated and only
+// inflated when the preview, search or an export needs them. In JSON it's
+// a plain string.
+type itemBody struct {
+	text     string
+	deflated []byte
+}
+
+func newItemBody(text string) itemBody {
+	if len(text) < minDeflatedBody {
+		return itemBody{text: text}
+	}
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.BestSpeed)
+	if _, err := io.WriteString(w, text); err != nil || w.Close() != nil {
+		return itemBody{text: text}
+	}
+	return itemBody{deflated: bytes.Clone(buf.Bytes())}
+}
+
+// String returns the description, inflating it if needed.
+func (b itemBody) String() string {
+	if b.deflated == nil {
+		return b.text
+	}
+	text, err := io.ReadAll(flate.NewReader(bytes.NewReader(b.deflated)))
+	if err != nil {
+		slog.Warn("error inflating item body", "err", err)
+	}
+	return string(text)
+}
+
+// empty reports whether there is no description, without inflating it.
+func (b itemBody) empty() bool {
+	return b.text == "" && b.deflated == nil
+}
+
+func (b itemBody) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.String())
+}
+
+func (b *itemBody) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*b = newItemBody(text)
+	return nil
+}
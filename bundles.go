@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// feedBundle is a starter set of feeds, offered by the setup wizard and
+// newseum bundles so a new user has something to read right away. Its feeds
+// are put in a category named after it.
+type feedBundle struct {
+	Name        string
+	Description string
+	Feeds       []FeedSource
+}
+
+var feedBundles = []feedBundle{
+	{
+		Name:        "Tech",
+		Description: "Hacker News, Ars Technica, The Verge, LWN",
+		Feeds: []FeedSource{
+			{Name: "Hacker News", URL: "https://news.ycombinator.com/rss"},
+			{Name: "Ars Technica", URL: "https://feeds.arstechnica.com/arstechnica/index"},
+			{Name: "The Verge", URL: "https://www.theverge.com/rss/index.xml"},
+			{Name: "LWN.net", URL: "https://lwn.net/headlines/rss"},
+		},
+	},
+	{
+		Name:        "World",
+		Description: "BBC World, NPR News, Al Jazeera, The Guardian",
+		Feeds: []FeedSource{
+			{Name: "BBC World", URL: "https://feeds.bbci.co.uk/news/world/rss.xml"},
+			{Name: "NPR News", URL: "https://feeds.npr.org/1001/rss.xml"},
+			{Name: "Al Jazeera", URL: "https://www.aljazeera.com/xml/rss/all.xml"},
+			{Name: "The Guardian World", URL: "https://www.theguardian.com/world/rss"},
+		},
+	},
+	{
+		Name:        "Podcasts",
+		Description: "The Changelog, Up First, 99% Invisible",
+		Feeds: []FeedSource{
+			{Name: "The Changelog", URL: "https://changelog.com/podcast/feed"},
+			{Name: "Up First", URL: "https://feeds.npr.org/510318/podcast.xml"},
+			{Name: "99% Invisible", URL: "https://feeds.simplecast.com/BqbsxVfO"},
+		},
+	},
+	{
+		Name:        "Go",
+		Description: "The Go Blog, Russ Cox, Dave Cheney, Eli Bendersky, Golang Weekly",
+		Feeds: []FeedSource{
+			{Name: "The Go Blog", URL: "https://go.dev/blog/feed.atom"},
+			{Name: "research!rsc", URL: "https://research.swtch.com/feed.atom"},
+			{Name: "Dave Cheney", URL: "https://dave.cheney.net/feed/atom"},
+			{Name: "Eli Bendersky", URL: "https://eli.thegreenplace.net/feeds/all.atom.xml"},
+			{Name: "Golang Weekly", URL: "https://golangweekly.com/rss/"},
+		},
+	},
+}
+
+// sources returns the feeds of a bundle, in its category.
+func (b feedBundle) sources() []FeedSource {
+	sources := make([]FeedSource, len(b.Feeds))
+	for i, source := range b.Feeds {
+		source.Category = b.Name
+		sources[i] = source
+	}
+	return sources
+}
+
+// findBundle looks a bundle up by name, ignoring case.
+func findBundle(name string) (feedBundle, bool) {
+	for _, b := range feedBundles {
+		if strings.EqualFold(b.Name, name) {
+			return b, true
+		}
+	}
+	return feedBundle{}, false
+}
+
+// runBundles lists the starter bundles, or with "add NAME..." subscribes to
+// them.
+func runBundles(args []string) error {
+	if len(args) == 0 {
+		for _, b := range feedBundles {
+			fmt.Printf("%-10s %s\n", strings.ToLower(b.Name), b.Description)
+		}
+		fmt.Println("\nSubscribe with: newseum bundles add NAME...")
+		return nil
+	}
+	if args[0] != "add" || len(args) < 2 {
+		return errors.New("usage: newseum bundles [add NAME...]")
+	}
+
+	var sources []FeedSource
+	for _, name := range args[1:] {
+		b, ok := findBundle(name)
+		if !ok {
+			return fmt.Errorf("no bundle named %q; run newseum bundles to list them", name)
+		}
+		sources = append(sources, b.sources()...)
+	}
+	added, err := appendFeedSources(sources)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Added %d feeds (%d already subscribed)\n", added, len(sources)-added)
+	return nil
+}
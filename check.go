@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mmcdole/gofeed"
+	"golang.org/x/net/html"
+)
+
+// maxCheckBody is the most of a feed newseum check reads.
+const maxCheckBody = 16 << 20
+
+// feedCheck is the outcome of checking one feed: a problem that stops it
+// from being fetched (failed), or one worth fixing (warnings), with what to
+// do about each.
+type feedCheck struct {
+	source   FeedSource
+	summary  string
+	failed   string
+	warnings []string
+	fixes    []string
+}
+
+// runCheck validates the config and feeds.csv and tries every feed, printing
+// a report with suggested fixes. It returns an error when something is
+// broken, so scripts can run it before committing config changes.
+func runCheck() error {
+	failures := 0
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("FAIL config: %v\n", err)
+		failures++
+	} else {
+		config = cfg
+		setupResolver()
+		if err := resolveSecrets(&config); err != nil {
+			fmt.Printf("FAIL config: %v\n", err)
+			failures++
+		} else {
+			path, _ := configPath()
+			fmt.Printf("ok   config %s\n", path)
+		}
+	}
+
+	sources, err := getFeedSources()
+	if err != nil {
+		fmt.Printf("FAIL feeds: %v\n", err)
+		return fmt.Errorf("%d problems found", failures+1)
+	}
+	for _, problem := range loadProblems() {
+		fmt.Printf("FAIL %s\n", problem)
+		failures++
+	}
+
+	checks := make([]feedCheck, len(sources))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < 5; w++ {
+		wg.Add(1)
+		goSafe(func() {
+			defer wg.Done()
+			for i := range jobs {
+				checks[i] = checkFeed(sources[i])
+			}
+		})
+	}
+	for i := range sources {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	seen := make(map[string]FeedSource)
+	for i, source := range sources {
+		if _, ok := source.query(); ok {
+			continue
+		}
+		key := normalizeFeedURL(source.URL)
+		if first, ok := seen[key]; ok {
+			checks[i].warnings = append(checks[i].warnings, "same feed as "+sourceLabel(first))
+			checks[i].fixes = append(checks[i].fixes, "remove one of the two rows from feeds.csv")
+		} else {
+			seen[key] = source
+		}
+	}
+
+	warnings := 0
+	for _, c := range checks {
+		label := sourceLabel(c.source)
+		switch {
+		case c.failed != "":
+			failures++
+			fmt.Printf("FAIL %s: %s\n", label, c.failed)
+		case len(c.warnings) > 0:
+			warnings++
+			fmt.Printf("warn %s: %s\n", label, strings.Join(c.warnings, "; "))
+		default:
+			fmt.Printf("ok   %s: %s\n", label, c.summary)
+		}
+		for _, fix := range c.fixes {
+			fmt.Printf("     → %s\n", fix)
+		}
+	}
+
+	fmt.Printf("\n%d feeds checked: %d failed, %d with warnings\n", len(sources), failures, warnings)
+	if failures > 0 {
+		return fmt.Errorf("%d problems found", failures)
+	}
+	return nil
+}
+
+// checkFeed fetches and parses one feed, noting redirects on the way.
+func checkFeed(source FeedSource) feedCheck {
+	c := feedCheck{source: source}
+	if query, ok := source.query(); ok {
+		if query == "" {
+			c.failed = "query feed without search words"
+			c.fixes = append(c.fixes, "put the words to match after query: in the URL column")
+		} else {
+			c.summary = "query feed"
+		}
+		return c
+	}
+	if source.Disabled {
+		c.summary = "disabled"
+		return c
+	}
+	if name, _, ok := source.plugin(); ok {
+		feed, _, err := fetchPlugin(context.Background(), gofeed.NewParser(), source)
+		if err != nil {
+			c.failed = err.Error()
+			c.fixes = append(c.fixes, "run newseum-"+name+" by hand with the request on standard input to see what it prints")
+			return c
+		}
+		if len(feed.Items) == 0 {
+			c.warnings = append(c.warnings, "the plugin gave no items")
+		}
+		c.summary = fmt.Sprintf("plugin %s, %d items", name, len(feed.Items))
+		return c
+	}
+	if _, _, ok := source.lemmy(); ok {
+		feed, _, err := fetchLemmy(context.Background(), source)
+		if err != nil {
+			c.failed = err.Error()
+			c.fixes = append(c.fixes, "check the community's name and instance in lemmy:COMMUNITY@INSTANCE")
+			return c
+		}
+		c.summary = fmt.Sprintf("Lemmy community, %d posts", len(feed.Items))
+		return c
+	}
+	if u, err := neturl.Parse(source.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		c.failed = "not an http(s) URL"
+		c.fixes = append(c.fixes, "the second column of feeds.csv must be the feed's full URL")
+		return c
+	}
+
+	client, err := feedClient(source.TLS)
+	if err != nil {
+		c.failed = err.Error()
+		c.fixes = append(c.fixes, "check the ca=, cert= and key= options")
+		return c
+	}
+	var permanent string
+	checking := *client
+	checking.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("too many redirects")
+		}
+		if status := req.Response.StatusCode; status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect {
+			permanent = req.URL.String()
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), "GET", source.URL, nil)
+	if err != nil {
+		c.failed = err.Error()
+		return c
+	}
+	req.Header.Set("User-Agent", "newseum")
+	resp, err := checking.Do(req)
+	if err != nil {
+		c.failed = err.Error()
+		c.fixes = append(c.fixes, networkFix(err))
+		return c
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCheckBody))
+	if err != nil {
+		c.failed = "error reading the feed: " + err.Error()
+		return c
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		c.failed = "http error: " + resp.Status
+		c.fixes = append(c.fixes, statusFix(resp.StatusCode))
+		return c
+	}
+	if permanent != "" && permanent != source.URL {
+		c.warnings = append(c.warnings, "moved permanently to "+permanent)
+		c.fixes = append(c.fixes, "change the URL in feeds.csv to "+permanent)
+	}
+
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(body))
+	if err != nil {
+		c.failed = "not a feed: " + err.Error()
+		if links := pageFeedLinks(body, resp.Request.URL); len(links) > 0 {
+			c.fixes = append(c.fixes, "this is a web page; its feeds are "+strings.Join(links, ", "))
+		} else if strings.Contains(resp.Header.Get("Content-Type"), "html") {
+			c.fixes = append(c.fixes, "this is a web page that doesn't link a feed; look on the site for an RSS or Atom link")
+		} else {
+			c.fixes = append(c.fixes, "the server sent something that isn't RSS, Atom or JSON Feed")
+		}
+		return c
+	}
+	if len(feed.Items) == 0 {
+		c.warnings = append(c.warnings, "the feed has no items")
+	}
+	c.summary = fmt.Sprintf("%s %s, %d items", feed.FeedType, feed.FeedVersion, len(feed.Items))
+	return c
+}
+
+// statusFix suggests what to do about an HTTP error status.
+func statusFix(status int) string {
+	switch {
+	case status == http.StatusNotFound || status == http.StatusGone:
+		return "the feed is gone; look on the site for its new address, or remove it"
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return "the server refused access; the feed may need a login, or block unknown clients"
+	case status == http.StatusTooManyRequests:
+		return "the server is rate limiting; try again later"
+	case status >= 500:
+		return "the server is failing; try again later"
+	}
+	return "check the URL in feeds.csv"
+}
+
+// networkFix suggests what to do about an error connecting to a feed.
+func networkFix(err error) string {
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &dnsErr):
+		return "the host name doesn't resolve; check it for typos"
+	case strings.Contains(err.Error(), "certificate"):
+		return "the server's certificate isn't trusted; add ca=/path/ca.pem, or insecure=true"
+	case errors.Is(err, os.ErrDeadlineExceeded) || strings.Contains(err.Error(), "timeout"):
+		return "the server didn't answer in time; check the address or try again later"
+	}
+	return "check the URL and your connection"
+}
+
+// pageFeedLinks returns the feeds a web page advertises with
+// <link rel="alternate">, made absolute.
+func pageFeedLinks(page []byte, base *neturl.URL) []string {
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return nil
+	}
+	var links []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "link" && hasRel(nodeAttr(n, "rel"), "alternate") {
+			switch nodeAttr(n, "type") {
+			case "application/rss+xml", "application/atom+xml", "application/feed+json", "application/json":
+				if href := nodeAttr(n, "href"); href != "" {
+					links = append(links, resolveURL(base, href))
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+	return links
+}
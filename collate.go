@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// foldText prepares text for searching: accents are removed, compatibility
+// forms such as full-width letters unified and case folded, so "Café" and
+// "CAFE" both match "cafe". Turkish dotless ı is folded to i as well, like
+// dotted İ already is, so "Istanbul" matches "İstanbul" and "ıstanbul".
+func foldText(s string) string {
+	t := transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), runes.Map(foldDotlessI), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		folded = s
+	}
+	return cases.Fold().String(folded)
+}
+
+// foldDotlessI maps Turkish dotless ı to i, which case folding leaves
+// apart.
+func foldDotlessI(r rune) rune {
+	if r == 'ı' {
+		return 'i'
+	}
+	return r
+}
+
+var (
+	collatorMutex sync.Mutex
+	collator      *collate.Collator
+)
+
+// lessName orders feed names, categories and the like by the collation
+// rules of the user's language, ignoring case.
+func lessName(a, b string) bool {
+	collatorMutex.Lock()
+	defer collatorMutex.Unlock()
+	if collator == nil {
+		collator = collate.New(userLanguage(), collate.IgnoreCase, collate.Loose)
+	}
+	return collator.CompareString(a, b) < 0
+}
+
+// userLanguage reads the language from the locale environment variables,
+// e.g. "sv_SE.UTF-8" from LANG, falling back to English.
+func userLanguage() language.Tag {
+	for _, name := range []string{"LC_ALL", "LC_COLLATE", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		value, _, _ = strings.Cut(value, ".")
+		value, _, _ = strings.Cut(value, "@")
+		if value == "C" || value == "POSIX" {
+			break
+		}
+		if tag, err := language.Parse(strings.ReplaceAll(value, "_", "-")); err == nil {
+			return tag
+		}
+	}
+	return language.English
+}
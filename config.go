@@ -0,0 +1,564 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the global settings read from ~/.config/newseum/config.
+type Config struct {
+	TTSCommand string
+	Player     string
+	// ImageViewer shows the images of descriptions instead of the browser.
+	ImageViewer string
+	Detach      bool
+	// Opener opens web pages instead of the desktop's browser: the name of
+	// a template or a command line with %u for the link and %b for
+	// TerminalBrowser.
+	Opener          string
+	TerminalBrowser string
+	// ScreenReader keeps the cursor on the selected row, draws no
+	// decorative glyphs and announces the selection with AnnounceCommand.
+	ScreenReader    bool
+	AnnounceCommand string
+
+	Theme          string
+	NoHyperlinks   bool
+	NoMouse        bool
+	AutoFeedColors bool
+	SectionHeaders bool
+
+	// ScrollLines is how far a wheel tick moves, ScrollThrottle the least
+	// time between ticks that are acted on, and ScrollViewport scrolls the
+	// lists without moving the selection.
+	ScrollLines    int
+	ScrollThrottle time.Duration
+	ScrollViewport bool
+
+	Archive string
+	// HideLanguages are the languages whose items are hidden at startup.
+	HideLanguages []string
+	// FuzzySearch makes searches match like fzf unless they start with '.
+	FuzzySearch bool
+
+	Prefetch bool
+	// DataSaver turns off downloads that weren't asked for, for metered
+	// connections.
+	DataSaver bool
+	SyncFile  string
+	// PreferIP is "4" or "6" to try that IP version first when connecting.
+	PreferIP string
+	// DNS is a DNS server (host[:port]) or DNS-over-HTTPS URL to look up
+	// hosts with instead of the system resolver.
+	DNS string
+	// KeepTrackers leaves tracking parameters in links; StripParams adds to
+	// the ones removed and KeepParams exempts some.
+	KeepTrackers bool
+	StripParams  []string
+	KeepParams   []string
+	// KeepAMP opens AMP and mobile links as they are instead of as the
+	// desktop page.
+	KeepAMP bool
+	// PreviewWidth is the widest the preview's text column gets; 0 fills
+	// the pane.
+	PreviewWidth int
+	// ParagraphSpacing is the number of blank lines between paragraphs.
+	ParagraphSpacing int
+	Hyphenate        bool
+	Justify          bool
+	// StartTab is the name of the tab shown at startup.
+	StartTab string
+	// OpenUnread is how many items O offers to open.
+	OpenUnread int
+	// MaxAge hides unstarred items older than this; 0 shows them all.
+	MaxAge time.Duration
+	// MaxItems caps how many of each feed's newest items are shown; 0 shows
+	// them all.
+	MaxItems int
+	// InterleaveWindow is the span of time within which the interleaved
+	// order lets the feeds take turns.
+	InterleaveWindow time.Duration
+
+	DownloadDir      string
+	TorrentClient    string
+	TorrentRPCSecret string
+	// KeepEpisodes is how many downloads of each feed are kept, and
+	// DeletePlayedAfter how long played ones are; 0 keeps them all.
+	KeepEpisodes      int
+	DeletePlayedAfter time.Duration
+	// ArchiveDir is where K saves snapshots of articles, which
+	// ArchiveStarred also does when an item is starred.
+	ArchiveDir     string
+	ArchiveStarred bool
+
+	Backend         string
+	BackendURL      string
+	BackendUser     string
+	BackendPassword string
+
+	// The gpodder.net (or opodsync) account podcasts are synced with.
+	GPodderURL      string
+	GPodderUser     string
+	GPodderPassword string
+	GPodderDevice   string
+
+	// DaemonInterval is how often newseum daemon fetches the feeds.
+	DaemonInterval time.Duration
+	// WebSubListen is the address the daemon takes WebSub pushes on, and
+	// WebSubCallback the URL hubs reach it at.
+	WebSubListen   string
+	WebSubCallback string
+	// WebListen is the address the daemon serves its web page on, and
+	// MetricsListen the one it serves Prometheus metrics on.
+	WebListen     string
+	MetricsListen string
+
+	// UpdateCheck looks for a newer release at most once a day.
+	UpdateCheck bool
+
+	PluginActions []PluginAction
+	// LemmyInstance is the Lemmy instance the user has an account on, which
+	// Lemmy communities are read through so their comments open there.
+	LemmyInstance string
+
+	Searches []SavedSearch
+}
+
+// SavedSearch is a named query shown as its own tab.
+type SavedSearch struct {
+	Name  string
+	Query string
+}
+
+var config Config
+
+// configFile and feedsFile replace the default locations of the config and
+// feeds.csv when given with -config and -feeds.
+var configFile, feedsFile string
+
+// profile is the name of the profile in use, from -profile or
+// NEWSEUM_PROFILE. Each profile has its own config, feeds, state and cache.
+var profile string
+
+// setProfile selects a profile, rejecting names that aren't a plain
+// directory name.
+func setProfile(name string) error {
+	if name != "" && (name == "." || name == ".." || strings.ContainsAny(name, `/\`)) {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	profile = name
+	return nil
+}
+
+// profileDir returns the newseum directory under base, which is a
+// directory of its own for each profile.
+func profileDir(base string) string {
+	if profile == "" {
+		return filepath.Join(base, "newseum")
+	}
+	return filepath.Join(base, "newseum", "profiles", profile)
+}
+
+// configDir returns the newseum directory under XDG_CONFIG_HOME (or ~/.config).
+func configDir() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("unable to determine home directory: %v", err)
+		}
+		dir = filepath.Join(homeDir, ".config")
+	}
+	return profileDir(dir), nil
+}
+
+// stateDir returns the newseum directory under XDG_STATE_HOME (or
+// ~/.local/state), where logs and other non-essential data are kept.
+func stateDir() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("unable to determine home directory: %v", err)
+		}
+		dir = filepath.Join(homeDir, ".local", "state")
+	}
+	return profileDir(dir), nil
+}
+
+// cacheDir returns the newseum directory under XDG_CACHE_HOME (or
+// ~/.cache), for data that can be downloaded again.
+func cacheDir() (string, error) {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("unable to determine home directory: %v", err)
+		}
+		dir = filepath.Join(homeDir, ".cache")
+	}
+	return profileDir(dir), nil
+}
+
+// dataDir returns the newseum directory under XDG_DATA_HOME (or
+// ~/.local/share), for data the user keeps.
+func dataDir() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("unable to determine home directory: %v", err)
+		}
+		dir = filepath.Join(homeDir, ".local", "share")
+	}
+	return profileDir(dir), nil
+}
+
+// expandPath expands $VARIABLES and a leading ~ in a path from the config
+// or feeds.csv.
+func expandPath(path string) string {
+	path = os.ExpandEnv(path)
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(homeDir, path[1:])
+		}
+	}
+	return path
+}
+
+// moveOldFile moves a file from where an older version kept it to path,
+// unless path exists already, and returns the path to use: the old one if
+// it couldn't be moved.
+func moveOldFile(old, path string) string {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return path
+	}
+	if _, err := os.Stat(old); err != nil {
+		return path
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		slog.Warn("error moving file", "from", old, "to", path, "err", err)
+		return old
+	}
+	if err := os.Rename(old, path); err != nil {
+		slog.Warn("error moving file", "from", old, "to", path, "err", err)
+		return old
+	}
+	os.Remove(old + ".lock")
+	slog.Info("moved file", "from", old, "to", path)
+	return path
+}
+
+// configPath returns the location of the config file.
+func configPath() (string, error) {
+	if configFile != "" {
+		return configFile, nil
+	}
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config"), nil
+}
+
+// loadConfig reads "key = value" lines from the config file. A missing file
+// is not an error; every setting has a usable default. Invalid lines are
+// skipped and kept in configProblems, so one typo doesn't stop newseum.
+func loadConfig() (Config, error) {
+	cfg := Config{ParagraphSpacing: 1, InterleaveWindow: 6 * time.Hour}
+
+	filePath, err := configPath()
+	if err != nil {
+		return cfg, err
+	}
+
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) && configFile == "" {
+		return cfg, nil
+	} else if err != nil {
+		return cfg, fmt.Errorf("error opening config %s: %v", filePath, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	var problems []string
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s:%d: expected key = value", filePath, lineNum))
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		before := cfg
+		if err := cfg.setOption(key, value); err != nil {
+			cfg = before
+			problems = append(problems, fmt.Sprintf("%s:%d: %v", filePath, lineNum, err))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return cfg, fmt.Errorf("error reading config %s: %v", filePath, err)
+	}
+	configProblems.set(problems)
+
+	return cfg, nil
+}
+
+// setOption applies a "key = value" line of the config file.
+func (cfg *Config) setOption(key, value string) error {
+	var err error
+	switch key {
+	case "tts-command":
+		cfg.TTSCommand = value
+	case "player":
+		cfg.Player = value
+	case "image-viewer":
+		cfg.ImageViewer = value
+	case "opener":
+		if _, ok := openerTemplates[value]; !ok && !strings.Contains(value, "%u") {
+			return fmt.Errorf("opener must be tmux-window, tmux-pane, wezterm or a command with %%u")
+		}
+		cfg.Opener = value
+	case "terminal-browser":
+		cfg.TerminalBrowser = value
+	case "screen-reader":
+		cfg.ScreenReader, err = strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("screen-reader must be true or false")
+		}
+	case "announce-command":
+		cfg.AnnounceCommand = value
+	case "detach":
+		cfg.Detach, err = strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("detach must be true or false")
+		}
+	case "section-headers":
+		cfg.SectionHeaders, err = strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("section-headers must be true or false")
+		}
+	case "hyperlinks":
+		hyperlinks, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("hyperlinks must be true or false")
+		}
+		cfg.NoHyperlinks = !hyperlinks
+	case "preview-width":
+		cfg.PreviewWidth, err = strconv.Atoi(value)
+		if err != nil || cfg.PreviewWidth < 0 {
+			return fmt.Errorf("preview-width must be a number")
+		}
+	case "paragraph-spacing":
+		cfg.ParagraphSpacing, err = strconv.Atoi(value)
+		if err != nil || cfg.ParagraphSpacing < 0 {
+			return fmt.Errorf("paragraph-spacing must be a number")
+		}
+	case "hyphenate":
+		cfg.Hyphenate, err = strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("hyphenate must be true or false")
+		}
+	case "justify":
+		cfg.Justify, err = strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("justify must be true or false")
+		}
+	case "mouse":
+		mouse, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("mouse must be true or false")
+		}
+		cfg.NoMouse = !mouse
+	case "scroll-lines":
+		cfg.ScrollLines, err = strconv.Atoi(value)
+		if err != nil || cfg.ScrollLines <= 0 {
+			return fmt.Errorf("scroll-lines must be a positive number")
+		}
+	case "scroll-throttle":
+		cfg.ScrollThrottle, err = time.ParseDuration(value)
+		if err != nil || cfg.ScrollThrottle < 0 {
+			return fmt.Errorf("scroll-throttle must be a duration like 30ms")
+		}
+	case "scroll-mode":
+		if value != "cursor" && value != "viewport" {
+			return fmt.Errorf("scroll-mode must be cursor or viewport")
+		}
+		cfg.ScrollViewport = value == "viewport"
+	case "theme":
+		if _, ok := findTheme(value); !ok {
+			return fmt.Errorf("unknown theme %q", value)
+		}
+		cfg.Theme = value
+	case "auto-feed-colors":
+		cfg.AutoFeedColors, err = strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("auto-feed-colors must be true or false")
+		}
+	case "fuzzy-search":
+		cfg.FuzzySearch, err = strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("fuzzy-search must be true or false")
+		}
+	case "start-tab":
+		cfg.StartTab = value
+	case "archive":
+		cfg.Archive = value
+	case "prefetch":
+		cfg.Prefetch, err = strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("prefetch must be true or false")
+		}
+	case "data-saver":
+		cfg.DataSaver, err = strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("data-saver must be true or false")
+		}
+	case "strip-trackers":
+		strip, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("strip-trackers must be true or false")
+		}
+		cfg.KeepTrackers = !strip
+	case "strip-params":
+		cfg.StripParams = append(cfg.StripParams, strings.Fields(strings.ReplaceAll(value, ",", " "))...)
+	case "keep-params":
+		cfg.KeepParams = append(cfg.KeepParams, strings.Fields(strings.ReplaceAll(value, ",", " "))...)
+	case "unwrap-amp":
+		unwrap, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("unwrap-amp must be true or false")
+		}
+		cfg.KeepAMP = !unwrap
+	case "open-unread":
+		cfg.OpenUnread, err = strconv.Atoi(value)
+		if err != nil || cfg.OpenUnread <= 0 {
+			return fmt.Errorf("open-unread must be a positive number")
+		}
+	case "max-items":
+		cfg.MaxItems, err = strconv.Atoi(value)
+		if err != nil || cfg.MaxItems < 0 {
+			return fmt.Errorf("max-items must be a number")
+		}
+	case "max-age":
+		var ok bool
+		cfg.MaxAge, ok = parseAge(value)
+		if !ok {
+			return fmt.Errorf("max-age must be a number of days (30d), weeks (8w) or hours (12h)")
+		}
+	case "interleave-window":
+		var ok bool
+		cfg.InterleaveWindow, ok = parseAge(value)
+		if !ok || cfg.InterleaveWindow <= 0 {
+			return fmt.Errorf("interleave-window must be a number of days (1d), weeks (1w) or hours (6h)")
+		}
+	case "prefer-ip":
+		if value != "4" && value != "6" {
+			return fmt.Errorf("prefer-ip must be 4 or 6")
+		}
+		cfg.PreferIP = value
+	case "dns":
+		cfg.DNS = value
+	case "sync-file":
+		cfg.SyncFile = expandPath(value)
+	case "download-dir":
+		cfg.DownloadDir = expandPath(value)
+	case "archive-dir":
+		cfg.ArchiveDir = expandPath(value)
+	case "hide-languages":
+		cfg.HideLanguages = append(cfg.HideLanguages, strings.Fields(strings.ReplaceAll(value, ",", " "))...)
+	case "archive-starred":
+		cfg.ArchiveStarred, err = strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("archive-starred must be true or false")
+		}
+	case "keep-episodes":
+		cfg.KeepEpisodes, err = strconv.Atoi(value)
+		if err != nil || cfg.KeepEpisodes < 0 {
+			return fmt.Errorf("keep-episodes must be a number")
+		}
+	case "delete-played-after":
+		var ok bool
+		cfg.DeletePlayedAfter, ok = parseAge(value)
+		if !ok {
+			return fmt.Errorf("delete-played-after must be a number of days (7d), weeks (2w) or hours (12h)")
+		}
+	case "torrent-client":
+		cfg.TorrentClient = value
+	case "torrent-rpc-secret":
+		cfg.TorrentRPCSecret = value
+	case "search":
+		name, query, found := strings.Cut(value, ":")
+		if !found {
+			return fmt.Errorf("expected search = name: query")
+		}
+		cfg.Searches = append(cfg.Searches, SavedSearch{
+			Name:  strings.TrimSpace(name),
+			Query: strings.TrimSpace(query),
+		})
+	case "backend":
+		cfg.Backend = value
+	case "backend-url":
+		cfg.BackendURL = value
+	case "backend-user":
+		cfg.BackendUser = value
+	case "backend-password":
+		cfg.BackendPassword = value
+	case "gpodder-url":
+		cfg.GPodderURL = value
+	case "gpodder-user":
+		cfg.GPodderUser = value
+	case "gpodder-password":
+		cfg.GPodderPassword = value
+	case "gpodder-device":
+		cfg.GPodderDevice = value
+	case "daemon-interval":
+		cfg.DaemonInterval, err = time.ParseDuration(value)
+		if err != nil || cfg.DaemonInterval < time.Minute {
+			return fmt.Errorf("daemon-interval must be a duration of at least a minute, like 15m")
+		}
+	case "websub-listen":
+		cfg.WebSubListen = value
+	case "websub-callback":
+		cfg.WebSubCallback = value
+	case "web-listen":
+		cfg.WebListen = value
+	case "metrics-listen":
+		cfg.MetricsListen = value
+	case "plugin-action":
+		label, rest, found := strings.Cut(value, ":")
+		fields := strings.Fields(rest)
+		if !found || strings.TrimSpace(label) == "" || len(fields) != 2 {
+			return fmt.Errorf("expected plugin-action = label: plugin action")
+		}
+		cfg.PluginActions = append(cfg.PluginActions, PluginAction{
+			Label:  strings.TrimSpace(label),
+			Plugin: fields[0],
+			Action: fields[1],
+		})
+	case "lemmy-instance":
+		cfg.LemmyInstance = strings.TrimSuffix(value, "/")
+	case "update-check":
+		cfg.UpdateCheck, err = strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("update-check must be true or false")
+		}
+	default:
+		return fmt.Errorf("unknown option %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The daemon takes JSON-RPC 2.0 requests on a unix socket, so scripts and
+// editor plugins can control it. Requests and responses are JSON values, one
+// after another on the connection; responses end with a newline:
+//
+//	echo '{"jsonrpc":"2.0","id":1,"method":"items.list","params":{"unread":true}}' |
+//		socat - UNIX-CONNECT:~/.local/state/newseum/daemon.sock
+
+// JSON-RPC error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// rpcItem is an item as the control API returns it. Key identifies it in
+// items.markRead.
+type rpcItem struct {
+	Key      string    `json:"key"`
+	Title    string    `json:"title"`
+	Feed     string    `json:"feed"`
+	FeedURL  string    `json:"feedUrl"`
+	Category string    `json:"category,omitempty"`
+	Link     string    `json:"link,omitempty"`
+	Date     time.Time `json:"date"`
+	Read     bool      `json:"read"`
+	Starred  bool      `json:"starred"`
+}
+
+// rpcFeed is a subscription as the control API takes and returns it.
+type rpcFeed struct {
+	URL      string `json:"url"`
+	Name     string `json:"name,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+func controlSocketPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "daemon.sock"), nil
+}
+
+// startControlSocket listens on the control socket until ctx is done. It
+// fails if another daemon is already listening there.
+func startControlSocket(ctx context.Context) error {
+	path, err := controlSocketPath()
+	if err != nil {
+		return err
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("newseum daemon is already running (%s)", path)
+	}
+	// A socket left behind by a daemon that didn't exit cleanly
+	os.Remove(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %v", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		slog.Warn("error restricting control socket", "path", path, "err", err)
+	}
+
+	goSafe(func() {
+		<-ctx.Done()
+		listener.Close()
+	})
+	goSafe(func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					slog.Error("error accepting control connection", "err", err)
+				}
+				return
+			}
+			goSafe(func() { serveControl(ctx, conn) })
+		}
+	})
+	return nil
+}
+
+// serveControl answers the requests on one connection until it closes.
+func serveControl(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	decoder := json.NewDecoder(bufio.NewReader(conn))
+	encoder := json.NewEncoder(conn)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			var syntax *json.SyntaxError
+			if errors.As(err, &syntax) {
+				encoder.Encode(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"),
+					Error: &rpcError{rpcParseError, err.Error()}})
+			}
+			return
+		}
+
+		var req rpcRequest
+		resp := rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null")}
+		if err := json.Unmarshal(raw, &req); err != nil || req.JSONRPC != "2.0" || req.Method == "" {
+			resp.Error = &rpcError{rpcInvalidRequest, "invalid request"}
+		} else {
+			if req.ID != nil {
+				resp.ID = req.ID
+			}
+			result, err := callControl(ctx, req.Method, req.Params)
+			var rerr *rpcError
+			switch {
+			case errors.As(err, &rerr):
+				resp.Error = rerr
+			case err != nil:
+				resp.Error = &rpcError{rpcServerError, err.Error()}
+			default:
+				resp.Result = result
+			}
+			// Notifications, which have no id, get no response
+			if req.ID == nil {
+				continue
+			}
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// callControl runs one method of the control API.
+func callControl(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "items.list":
+		var p struct {
+			Unread bool   `json:"unread"`
+			Feed   string `json:"feed"`
+			Limit  int    `json:"limit"`
+		}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		items, _, err := savedItems()
+		if err != nil {
+			return nil, err
+		}
+		listed := []rpcItem{}
+		for _, item := range items {
+			if p.Unread && item.Read {
+				continue
+			}
+			if p.Feed != "" && item.FeedURL != p.Feed && !strings.EqualFold(item.FeedTitle, p.Feed) {
+				continue
+			}
+			listed = append(listed, rpcItem{
+				Key:      itemKey(item),
+				Title:    item.Title,
+				Feed:     item.FeedTitle,
+				FeedURL:  item.FeedURL,
+				Category: item.Category,
+				Link:     item.Link,
+				Date:     item.Date,
+				Read:     item.Read,
+				Starred:  item.Starred,
+			})
+			if len(listed) == p.Limit {
+				break
+			}
+		}
+		return listed, nil
+
+	case "items.markRead":
+		p := struct {
+			Key  string `json:"key"`
+			Read *bool  `json:"read"`
+		}{}
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		items, state, err := savedItems()
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if itemKey(item) != p.Key {
+				continue
+			}
+			item.Read = p.Read == nil || *p.Read
+			if err := state.update(item); err != nil {
+				return nil, err
+			}
+			return map[string]any{"key": p.Key, "read": item.Read}, nil
+		}
+		return nil, &rpcError{rpcInvalidParams, "no item with key " + p.Key}
+
+	case "feeds.list":
+		sources, err := getFeedSources()
+		if err != nil {
+			return nil, err
+		}
+		feeds := []rpcFeed{}
+		for _, source := range sources {
+			feeds = append(feeds, rpcFeed{URL: source.URL, Name: source.Name, Category: source.Category})
+		}
+		return feeds, nil
+
+	case "feeds.add":
+		var p rpcFeed
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if p.URL == "" {
+			return nil, &rpcError{rpcInvalidParams, "url is required"}
+		}
+		if err := checkFeedURL(p.URL); err != nil {
+			return nil, &rpcError{rpcInvalidParams, err.Error()}
+		}
+		added, err := appendFeedSources([]FeedSource{{URL: p.URL, Name: p.Name, Category: p.Category}})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]bool{"added": added > 0}, nil
+
+	case "refresh":
+		_, result, err := daemonRefresh(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	return nil, &rpcError{rpcMethodNotFound, "unknown method " + method}
+}
+
+// decodeParams reads a method's named parameters, which may be left out.
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{rpcInvalidParams, "invalid params: " + err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// activeApp is the running TUI. It is stopped before a crash is reported so
+// the terminal leaves raw mode and mouse reporting is switched off.
+var activeApp *tview.Application
+
+// handlePanic must be deferred at the top of main and of every goroutine.
+// It restores the terminal, reports the panic with the path of a crash log
+// holding the stack trace, and exits.
+func handlePanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if activeApp != nil {
+		activeApp.Stop()
+	}
+
+	report := fmt.Sprintf("newseum crashed at %s: %v\n\n%s", time.Now().Format(time.RFC3339), r, debug.Stack())
+	fmt.Fprintf(os.Stderr, "newseum crashed: %v\n", r)
+	if path, err := writeCrashLog(report); err == nil {
+		fmt.Fprintf(os.Stderr, "The stack trace was written to %s\n", path)
+	} else {
+		fmt.Fprint(os.Stderr, report)
+	}
+	os.Exit(2)
+}
+
+// goSafe runs f in a new goroutine that reports panics through handlePanic.
+func goSafe(f func()) {
+	go func() {
+		defer handlePanic()
+		f()
+	}()
+}
+
+func writeCrashLog(report string) (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, "crash.log")
+	if err := os.WriteFile(path, []byte(report), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
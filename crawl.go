@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// crawlDelay is the minimum time between two requests to the same host.
+	crawlDelay = time.Second
+	// articleCacheTTL is how long a downloaded page is reused.
+	articleCacheTTL = 7 * 24 * time.Hour
+	maxArticleSize  = 8 << 20
+)
+
+// crawler downloads article pages politely: one request at a time per host
+// with a pause between them, honoring robots.txt, and keeping pages in a
+// disk cache so each one is only downloaded once.
+type crawler struct {
+	mutex sync.Mutex
+	hosts map[string]*crawlHost
+}
+
+type crawlHost struct {
+	sync.Mutex // held while a request to the host is in flight
+	last       time.Time
+	robots     []robotsRule
+	robotsRead bool
+}
+
+var articleCrawler = &crawler{hosts: make(map[string]*crawlHost)}
+
+func (c *crawler) host(name string) *crawlHost {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	h, ok := c.hosts[name]
+	if !ok {
+		h = &crawlHost{}
+		c.hosts[name] = h
+	}
+	return h
+}
+
+// fetch returns the body of the page at rawURL, from the cache if possible.
+func (c *crawler) fetch(rawURL string) ([]byte, error) {
+	if body, err := readArticleCache(rawURL, articleCacheTTL); err == nil {
+		return body, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	h := c.host(u.Host)
+	h.Lock()
+	defer h.Unlock()
+
+	if !h.robotsRead {
+		h.robots = h.fetchRobots(u)
+		h.robotsRead = true
+	}
+	if !robotsAllowed(h.robots, u.RequestURI()) {
+		return nil, fmt.Errorf("%s is disallowed by robots.txt", rawURL)
+	}
+
+	body, err := h.get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeArticleCache(rawURL, body); err != nil {
+		slog.Warn("error caching article", "url", rawURL, "err", err)
+	}
+	return body, nil
+}
+
+// get performs a request once the host's crawl delay has passed. The
+// caller must hold the host's lock.
+func (h *crawlHost) get(rawURL string) ([]byte, error) {
+	if wait := crawlDelay - time.Since(h.last); wait > 0 {
+		time.Sleep(wait)
+	}
+	defer func() { h.last = time.Now() }()
+
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "newseum")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxArticleSize))
+}
+
+type robotsRule struct {
+	allow bool
+	path  string
+}
+
+// fetchRobots reads the rules in robots.txt that apply to newseum. A
+// missing or unreadable file allows everything.
+func (h *crawlHost) fetchRobots(u *url.URL) []robotsRule {
+	robotsURL := u.Scheme + "://" + u.Host + "/robots.txt"
+	body, err := h.get(robotsURL)
+	if err != nil {
+		slog.Debug("no robots.txt", "url", robotsURL, "err", err)
+		return nil
+	}
+	return parseRobots(body)
+}
+
+// parseRobots returns the rules of the groups for newseum, or for * when
+// no group names newseum.
+func parseRobots(body []byte) []robotsRule {
+	var ours, wildcard []robotsRule
+	var agents []string
+	inRules := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				agents = nil
+				inRules = false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{allow: key == "allow", path: value}
+			for _, agent := range agents {
+				if agent == "newseum" {
+					ours = append(ours, rule)
+				} else if agent == "*" {
+					wildcard = append(wildcard, rule)
+				}
+			}
+		}
+	}
+
+	if ours != nil {
+		return ours
+	}
+	return wildcard
+}
+
+// robotsAllowed applies the longest matching rule to path.
+func robotsAllowed(rules []robotsRule, path string) bool {
+	allowed, longest := true, -1
+	for _, rule := range rules {
+		if strings.HasPrefix(path, rule.path) && len(rule.path) > longest {
+			allowed, longest = rule.allow, len(rule.path)
+		}
+	}
+	return allowed
+}
+
+func articleCachePath(rawURL string) (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(dir, "articles", hex.EncodeToString(sum[:])+".html"), nil
+}
+
+// readArticleCache returns a cached page no older than maxAge; 0 accepts
+// any age.
+func readArticleCache(rawURL string, maxAge time.Duration) ([]byte, error) {
+	path, err := articleCachePath(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if maxAge > 0 && time.Since(info.ModTime()) > maxAge {
+		return nil, fmt.Errorf("cached copy of %s expired", rawURL)
+	}
+	return os.ReadFile(path)
+}
+
+func writeArticleCache(rawURL string, body []byte) error {
+	path, err := articleCachePath(rawURL)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	defer forgetArticleText(rawURL)
+	return os.WriteFile(path, body, 0o644)
+}
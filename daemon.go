@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultDaemonInterval is how often the daemon fetches the feeds when
+// daemon-interval isn't set.
+const defaultDaemonInterval = 15 * time.Minute
+
+var (
+	// daemonCacheMutex serializes the daemon's updates of the item cache,
+	// which come from both its fetches and pushed content.
+	daemonCacheMutex sync.Mutex
+	// pushedDuringFetch are the items hubs pushed since the running fetch
+	// started, which its results lack. Guarded by daemonCacheMutex.
+	pushedDuringFetch []FeedItem
+	// daemonFetchMutex keeps the daemon to one fetch at a time.
+	daemonFetchMutex sync.Mutex
+)
+
+// runDaemon fetches the feeds every daemon-interval until interrupted,
+// keeping the item cache that newseum --no-fetch shows up to date. With
+// websub-listen set, feeds whose hubs push new content are updated as soon
+// as it arrives; the others, and pushes that never come, are polled.
+func runDaemon() error {
+	interval := config.DaemonInterval
+	if interval == 0 {
+		interval = defaultDaemonInterval
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var hubs *webSub
+	if config.WebSubListen != "" {
+		var err error
+		if hubs, err = startWebSub(ctx, cachePushedItems); err != nil {
+			return err
+		}
+	}
+
+	if config.WebListen != "" {
+		startWebUI(ctx)
+		fmt.Printf("Serving the timeline on %s\n", config.WebListen)
+	}
+	if config.MetricsListen != "" {
+		startMetrics(ctx)
+		fmt.Printf("Serving metrics on %s/metrics\n", config.MetricsListen)
+	}
+
+	if err := startControlSocket(ctx); err != nil {
+		return err
+	}
+
+	fmt.Printf("Fetching feeds every %s; Ctrl-C to stop\n", interval)
+	for {
+		sources, _, err := daemonRefresh(ctx)
+		switch {
+		case ctx.Err() != nil:
+		case err != nil:
+			slog.Error("error fetching feeds", "err", err)
+		case hubs != nil:
+			hubs.subscribeAll(ctx, sources)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// daemonFetchResult sums up one of the daemon's fetches.
+type daemonFetchResult struct {
+	Feeds  int `json:"feeds"`
+	Failed int `json:"failed"`
+	New    int `json:"new"`
+}
+
+// daemonRefresh fetches the feeds once and saves their items to the cache.
+// feeds.csv is read again each time, so new subscriptions are picked up.
+// Refreshes asked for over the control socket wait for one in progress.
+func daemonRefresh(ctx context.Context) ([]FeedSource, daemonFetchResult, error) {
+	daemonFetchMutex.Lock()
+	defer daemonFetchMutex.Unlock()
+
+	var result daemonFetchResult
+	sources, err := getFeedSources()
+	if err != nil {
+		return nil, result, err
+	}
+	start := time.Now()
+	daemonCacheMutex.Lock()
+	pushedDuringFetch = nil
+	daemonCacheMutex.Unlock()
+	items, err := fetchFeeds(ctx, sources, func(p fetchProgress) {
+		recordFetch(p)
+		if p.Err != nil {
+			result.Failed++
+			slog.Error("error fetching feed", "url", p.Source.URL, "err", p.Err)
+		}
+	})
+	if err != nil {
+		return sources, result, err
+	}
+	if err := ctx.Err(); err != nil {
+		return sources, result, err
+	}
+
+	daemonCacheMutex.Lock()
+	items = mergePushedItems(items, pushedDuringFetch)
+	pushedDuringFetch = nil
+	result.Feeds = len(sources)
+	result.New = countNewItems(loadItemCache(), items)
+	err = saveItemCache(items)
+	daemonCacheMutex.Unlock()
+	if err != nil {
+		return sources, result, fmt.Errorf("error saving item cache: %v", err)
+	}
+	recordRefresh(time.Since(start))
+	slog.Info("fetched feeds", "feeds", result.Feeds, "failed", result.Failed, "new", result.New, "elapsed", time.Since(start))
+	return sources, result, nil
+}
+
+// cachePushedItems adds the items a hub pushed to the feed's in the cache.
+// Hubs often push only the new entries, so the cached ones are kept until
+// the next fetch replaces them.
+func cachePushedItems(source FeedSource, pushed []FeedItem) {
+	daemonCacheMutex.Lock()
+	defer daemonCacheMutex.Unlock()
+
+	pushedDuringFetch = append(pushedDuringFetch, pushed...)
+	cached := loadItemCache()
+	added := countNewItems(cached, pushed)
+	pushedKeys := make(map[string]bool)
+	for _, item := range pushed {
+		pushedKeys[itemKey(item)] = true
+	}
+	merged := pushed
+	for _, item := range cached[source.URL] {
+		if !pushedKeys[itemKey(item)] {
+			merged = append(merged, item)
+		}
+	}
+	cached[source.URL] = merged
+	var items []FeedItem
+	for _, saved := range cached {
+		items = append(items, saved...)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Date.After(items[j].Date)
+	})
+	if err := saveItemCache(items); err != nil {
+		slog.Error("error saving item cache", "err", err)
+		return
+	}
+	recordPush()
+	slog.Info("received pushed items", "url", source.URL, "new", added)
+}
+
+// mergePushedItems adds the pushed items a fetch's results lack to them,
+// keeping them newest first.
+func mergePushedItems(items, pushed []FeedItem) []FeedItem {
+	if len(pushed) == 0 {
+		return items
+	}
+	fetched := make(map[string]bool)
+	for _, item := range items {
+		fetched[itemKey(item)] = true
+	}
+	for _, item := range pushed {
+		if key := itemKey(item); !fetched[key] {
+			fetched[key] = true
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Date.After(items[j].Date)
+	})
+	return items
+}
+
+// countNewItems counts the items that aren't in the cache yet.
+func countNewItems(cached map[string][]FeedItem, items []FeedItem) int {
+	known := make(map[string]bool)
+	for _, saved := range cached {
+		for _, item := range saved {
+			known[itemKey(item)] = true
+		}
+	}
+	added := 0
+	for _, item := range items {
+		if !known[itemKey(item)] {
+			added++
+		}
+	}
+	return added
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// maxDiffCells bounds the work of diffing two texts word by word; longer
+// changes are shown as the whole old text followed by the new one.
+const maxDiffCells = 4_000_000
+
+// markUpdated flags the fetched items whose title or text changed since the
+// previous fetch, keeping what they said before, and carries over the flags
+// of items found changed by earlier fetches.
+func markUpdated(items, previous []FeedItem) {
+	if len(previous) == 0 {
+		return
+	}
+	byKey := make(map[string]FeedItem, len(previous))
+	for _, item := range previous {
+		byKey[itemKey(item)] = item
+	}
+	now := time.Now().UTC()
+	for i := range items {
+		old, ok := byKey[itemKey(items[i])]
+		if !ok {
+			continue
+		}
+		if old.ContentHash != "" && items[i].ContentHash != "" && old.ContentHash != items[i].ContentHash {
+			items[i].UpdatedAt = now
+			items[i].PreviousTitle, items[i].PreviousDescription = old.Title, old.Description
+		} else {
+			items[i].UpdatedAt = old.UpdatedAt
+			items[i].PreviousTitle, items[i].PreviousDescription = old.PreviousTitle, old.PreviousDescription
+		}
+	}
+}
+
+// showChanges shows what changed in the selected item's title and text the
+// last time a fetch found it edited.
+func (u *UI) showChanges() {
+	index := u.selected()
+	if index < 0 {
+		return
+	}
+	item := u.items[index]
+	if item.UpdatedAt.IsZero() {
+		u.setStatus("This item hasn't changed since it was first fetched")
+		return
+	}
+	before := CleanString(item.PreviousTitle) + "\n\n" + htmlToText(item.PreviousDescription.String())
+	after := CleanString(item.Title) + "\n\n" + htmlToText(item.Description.String())
+	view := u.fullScreenView("changes", "Changes to "+item.Title)
+	theme := currentTheme()
+	view.SetText(theme.dim("Updated "+formatDate(item.UpdatedAt, u.now)) + "\n\n" + diffMarkup(before, after))
+}
+
+var diffTokens = regexp.MustCompile(`\s+|[^\s]+`)
+
+// diffMarkup marks the words removed from before and added in after the way
+// git diff --word-diff does, [-removed-]{+added+}, in red and green in color
+// themes.
+func diffMarkup(before, after string) string {
+	a := diffTokens.FindAllString(before, -1)
+	b := diffTokens.FindAllString(after, -1)
+
+	// Edits are usually small, so the common start and end are set aside
+	// before the quadratic part.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	removed, added := "[red]", "[green]"
+	if currentTheme().Mono {
+		removed, added = "[::d]", "[::b]"
+	}
+	var sb strings.Builder
+	emit := func(style string, tokens []string) {
+		if len(tokens) == 0 {
+			return
+		}
+		text := strings.Join(tokens, "")
+		if style == "" {
+			sb.WriteString(tview.Escape(text))
+			return
+		}
+		start, end := "[-", "-]"
+		if style == added {
+			start, end = "{+", "+}"
+		}
+		trimmed := strings.TrimRight(text, " \t\n")
+		sb.WriteString(style + tview.Escape(start+trimmed+end) + "[-::-]" + text[len(trimmed):])
+	}
+
+	emit("", a[:prefix])
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(midA)*len(midB) > maxDiffCells {
+		emit(removed, midA)
+		emit(added, midB)
+	} else {
+		for _, op := range diffTokenOps(midA, midB) {
+			switch op.kind {
+			case '-':
+				emit(removed, op.tokens)
+			case '+':
+				emit(added, op.tokens)
+			default:
+				emit("", op.tokens)
+			}
+		}
+	}
+	emit("", a[len(a)-suffix:])
+	return sb.String()
+}
+
+// diffOp is a run of tokens kept (' '), removed ('-') or added ('+').
+type diffOp struct {
+	kind   byte
+	tokens []string
+}
+
+// diffTokenOps turns a into b through the longest common subsequence of
+// their tokens.
+func diffTokenOps(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	add := func(kind byte, token string) {
+		if n := len(ops); n > 0 && ops[n-1].kind == kind {
+			ops[n-1].tokens = append(ops[n-1].tokens, token)
+			return
+		}
+		ops = append(ops, diffOp{kind, []string{token}})
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			add(' ', a[i])
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			add('-', a[i])
+			i++
+		default:
+			add('+', b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		add('-', a[i])
+	}
+	for ; j < len(b); j++ {
+		add('+', b[j])
+	}
+	return ops
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var dialer = &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+
+// dohClient sends DNS-over-HTTPS queries. It keeps the default transport,
+// so the DoH server's own name is looked up by the system resolver.
+var dohClient = &http.Client{Timeout: 10 * time.Second}
+
+// setupResolver applies the prefer-ip and dns settings from the config to
+// the shared HTTP client. Without them the system resolver is used as is.
+func setupResolver() {
+	if config.PreferIP == "" && config.DNS == "" {
+		return
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialContext
+	httpClient.Transport = transport
+}
+
+// dialContext looks up a host with the configured resolver and connects to
+// its addresses in order, the preferred IP version first.
+func dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	ips, err := lookupIPs(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if config.PreferIP != "" {
+		prefer4 := config.PreferIP == "4"
+		sort.SliceStable(ips, func(i, j int) bool {
+			return (ips[i].To4() != nil) == prefer4 && (ips[j].To4() != nil) != prefer4
+		})
+	}
+
+	var firstErr error
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// lookupIPs resolves host through the DoH endpoint or DNS server from the
+// config, or the system resolver when neither is set.
+func lookupIPs(ctx context.Context, host string) ([]net.IP, error) {
+	if strings.HasPrefix(config.DNS, "https://") {
+		return dohLookup(ctx, host)
+	}
+
+	resolver := net.DefaultResolver
+	if config.DNS != "" {
+		server := config.DNS
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+	return resolver.LookupIP(ctx, "ip", host)
+}
+
+// dohLookup asks the DNS-over-HTTPS endpoint (RFC 8484) for the host's IPv4
+// and IPv6 addresses.
+func dohLookup(ctx context.Context, host string) ([]net.IP, error) {
+	var ips []net.IP
+	var lastErr error
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		found, err := dohQuery(ctx, host, qtype)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		ips = append(ips, found...)
+	}
+	if len(ips) == 0 {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, Server: config.DNS, IsNotFound: true}
+	}
+	return ips, nil
+}
+
+// dohQuery sends a single question to the DoH endpoint and returns the
+// addresses in the answer.
+func dohQuery(ctx context.Context, host string, qtype dnsmessage.Type) ([]net.IP, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return nil, fmt.Errorf("invalid host name %q", host)
+	}
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", config.DNS, bytes.NewReader(packed))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := dohClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("DNS-over-HTTPS lookup of %s failed: %v", host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DNS-over-HTTPS lookup of %s failed: %s", host, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+
+	var reply dnsmessage.Message
+	if err := reply.Unpack(body); err != nil {
+		return nil, fmt.Errorf("invalid DNS-over-HTTPS reply for %s: %v", host, err)
+	}
+	if reply.RCode != dnsmessage.RCodeSuccess {
+		return nil, &net.DNSError{Err: reply.RCode.String(), Name: host, Server: config.DNS,
+			IsNotFound: reply.RCode == dnsmessage.RCodeNameError}
+	}
+
+	var ips []net.IP
+	for _, answer := range reply.Answers {
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			ips = append(ips, net.IP(body.A[:]))
+		case *dnsmessage.AAAAResource:
+			ips = append(ips, net.IP(body.AAAA[:]))
+		}
+	}
+	return ips, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// downloadStallTimeout is how long a download may go without receiving
+// anything before it is given up. There is no limit on the whole download,
+// which for a long episode or video can take far longer.
+const downloadStallTimeout = time.Minute
+
+var (
+	downloadClientOnce sync.Once
+	downloadHTTPClient *http.Client
+)
+
+// downloadClient returns the HTTP client for enclosures. Unlike httpClient
+// it has no overall timeout; connecting, the TLS handshake and waiting for
+// the response headers are bounded on its transport instead. It is made on
+// first use so it picks up the resolver settings from setupResolver.
+func downloadClient() *http.Client {
+	downloadClientOnce.Do(func() {
+		base, ok := httpClient.Transport.(*http.Transport)
+		if !ok {
+			base = http.DefaultTransport.(*http.Transport)
+		}
+		transport := base.Clone()
+		if transport.DialContext == nil {
+			transport.DialContext = dialer.DialContext
+		}
+		transport.TLSHandshakeTimeout = 10 * time.Second
+		transport.ResponseHeaderTimeout = 30 * time.Second
+		downloadHTTPClient = &http.Client{Transport: transport}
+	})
+	return downloadHTTPClient
+}
+
+// stallReader cancels a download when reading from r makes no progress
+// for downloadStallTimeout.
+type stallReader struct {
+	r     io.Reader
+	timer *time.Timer
+}
+
+func (s *stallReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if n > 0 {
+		s.timer.Reset(downloadStallTimeout)
+	}
+	return n, err
+}
+
+// downloadDir returns download-dir from the config, or the user's
+// Downloads directory.
+func downloadDir() (string, error) {
+	if config.DownloadDir != "" {
+		return config.DownloadDir, nil
+	}
+	if dir := os.Getenv("XDG_DOWNLOAD_DIR"); dir != "" {
+		return dir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to determine home directory: %v", err)
+	}
+	return filepath.Join(homeDir, "Downloads"), nil
+}
+
+// downloadFile saves rawURL into the download directory and returns the
+// path it was written to. Existing files are never overwritten.
+func downloadFile(rawURL string) (string, error) {
+	dir, err := downloadDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("error creating download directory: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stalled := time.AfterFunc(downloadStallTimeout, cancel)
+	defer stalled.Stop()
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("error downloading %s: %v", rawURL, err)
+	}
+	req.Header.Set("User-Agent", "newseum")
+	resp, err := downloadClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error downloading %s: %v", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error downloading %s: %s", rawURL, resp.Status)
+	}
+
+	file, dest, err := createUnique(dir, downloadName(rawURL))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(file, &stallReader{r: resp.Body, timer: stalled}); err != nil {
+		file.Close()
+		os.Remove(dest)
+		if ctx.Err() != nil {
+			err = fmt.Errorf("nothing received for %v", downloadStallTimeout)
+		}
+		return "", fmt.Errorf("error downloading %s: %v", rawURL, err)
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	slog.Info("downloaded enclosure", "url", rawURL, "path", dest)
+	return dest, nil
+}
+
+// downloadName picks a file name from the last segment of the URL path.
+func downloadName(rawURL string) string {
+	name := "download"
+	if u, err := url.Parse(rawURL); err == nil {
+		if base := path.Base(u.Path); base != "." && base != "/" {
+			name = base
+		}
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// createUnique creates name in dir, adding a number before the extension
+// if a file by that name already exists.
+func createUnique(dir, name string) (*os.File, string, error) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for i := 0; ; i++ {
+		candidate := name
+		if i > 0 {
+			candidate = fmt.Sprintf("%s (%d)%s", stem, i, ext)
+		}
+		dest := filepath.Join(dir, candidate)
+		file, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("error creating %s: %v", dest, err)
+		}
+		return file, dest, nil
+	}
+}
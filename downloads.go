@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// download is an enclosure saved by downloadFile. Only files listed here
+// are ever deleted by the retention rules.
+type download struct {
+	Path      string    `json:"path"`
+	URL       string    `json:"url"`
+	FeedURL   string    `json:"feed_url"`
+	FeedTitle string    `json:"feed_title"`
+	Title     string    `json:"title"`
+	Time      time.Time `json:"time"`
+	Played    time.Time `json:"played,omitempty"`
+}
+
+// downloadsMutex guards downloads.json.
+var downloadsMutex sync.Mutex
+
+func downloadsPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "downloads.json"), nil
+}
+
+// loadDownloads reads the downloads list, dropping files that no longer
+// exist. The caller holds downloadsMutex.
+func loadDownloads() []download {
+	path, err := downloadsPath()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var all []download
+	if err := json.Unmarshal(data, &all); err != nil {
+		slog.Error("error reading downloads list", "path", path, "err", err)
+		return nil
+	}
+	var existing []download
+	for _, d := range all {
+		if _, err := os.Stat(d.Path); err == nil {
+			existing = append(existing, d)
+		}
+	}
+	return existing
+}
+
+// saveDownloads writes the downloads list. The caller holds downloadsMutex.
+func saveDownloads(downloads []download) error {
+	path, err := downloadsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(downloads, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// downloadEnclosure downloads one of an item's enclosures, records it and
+// applies the retention rules.
+func downloadEnclosure(item FeedItem, url string) (string, error) {
+	dest, err := downloadFile(url)
+	if err != nil {
+		return "", err
+	}
+
+	downloadsMutex.Lock()
+	downloads := append(loadDownloads(), download{
+		Path:      dest,
+		URL:       url,
+		FeedURL:   item.FeedURL,
+		FeedTitle: item.FeedTitle,
+		Title:     item.Title,
+		Time:      time.Now().UTC(),
+	})
+	err = saveDownloads(downloads)
+	downloadsMutex.Unlock()
+	if err != nil {
+		return dest, fmt.Errorf("error saving downloads list: %v", err)
+	}
+
+	uploadGPodderAction("download", item, url)
+	cleanupDownloads()
+	return dest, nil
+}
+
+// cleanupDownloads deletes the downloads that keep-episodes and
+// delete-played-after no longer keep, and returns how many it deleted.
+func cleanupDownloads() int {
+	if config.KeepEpisodes == 0 && config.DeletePlayedAfter == 0 {
+		return 0
+	}
+	downloadsMutex.Lock()
+	defer downloadsMutex.Unlock()
+
+	downloads := loadDownloads()
+	sort.SliceStable(downloads, func(i, j int) bool {
+		return downloads[i].Time.After(downloads[j].Time)
+	})
+	perFeed := make(map[string]int)
+	now := time.Now()
+	var kept []download
+	removed := 0
+	for _, d := range downloads {
+		perFeed[d.FeedURL]++
+		expired := config.DeletePlayedAfter > 0 && !d.Played.IsZero() && now.Sub(d.Played) > config.DeletePlayedAfter
+		surplus := config.KeepEpisodes > 0 && perFeed[d.FeedURL] > config.KeepEpisodes
+		if !expired && !surplus {
+			kept = append(kept, d)
+			continue
+		}
+		if err := os.Remove(d.Path); err != nil && !os.IsNotExist(err) {
+			slog.Error("error deleting old download", "path", d.Path, "err", err)
+			kept = append(kept, d)
+			continue
+		}
+		slog.Info("deleted old download", "path", d.Path)
+		removed++
+	}
+	if removed > 0 {
+		if err := saveDownloads(kept); err != nil {
+			slog.Error("error saving downloads list", "err", err)
+		}
+	}
+	return removed
+}
+
+// updateDownload changes the recorded download at path, or removes it from
+// the list when change returns false.
+func updateDownload(path string, change func(*download) bool) error {
+	downloadsMutex.Lock()
+	defer downloadsMutex.Unlock()
+	downloads := loadDownloads()
+	var kept []download
+	for _, d := range downloads {
+		if d.Path != path || change(&d) {
+			kept = append(kept, d)
+		}
+	}
+	return saveDownloads(kept)
+}
+
+// playFile plays a downloaded file with the player from the config, or
+// the desktop's default application.
+func playFile(path string) error {
+	if config.Player != "" {
+		player := strings.Fields(config.Player)
+		return launch(player[0], append(player[1:], path)...)
+	}
+	return openDefault(path)
+}
+
+// showDownloads lists the downloaded enclosures, newest first. Enter plays
+// one and x deletes it.
+func (u *UI) showDownloads() {
+	downloadsMutex.Lock()
+	downloads := loadDownloads()
+	downloadsMutex.Unlock()
+	sort.SliceStable(downloads, func(i, j int) bool {
+		return downloads[i].Time.After(downloads[j].Time)
+	})
+
+	list := tview.NewList().ShowSecondaryText(true)
+	list.SetBorder(true).SetTitle(" Downloads (Enter to play, x to delete, Esc to close) ")
+	list.SetBackgroundColor(tcell.ColorDefault)
+	if len(downloads) == 0 {
+		list.AddItem("No downloads yet; press e on an item and d to download an enclosure", "", 0, nil)
+	}
+	for _, d := range downloads {
+		size := ""
+		if info, err := os.Stat(d.Path); err == nil {
+			size = humanSize(info.Size()) + " · "
+		}
+		status := "downloaded " + formatDate(d.Time, u.now)
+		if !d.Played.IsZero() {
+			status += " · played " + formatDate(d.Played, u.now)
+		}
+		list.AddItem(tview.Escape(CleanString(d.FeedTitle)+" — "+CleanString(d.Title)),
+			tview.Escape(size+status+" · "+d.Path), 0, nil)
+	}
+
+	list.SetSelectedFunc(func(i int, _, _ string, _ rune) {
+		if i >= len(downloads) {
+			return
+		}
+		path := downloads[i].Path
+		if err := playFile(path); err != nil {
+			slog.Error("error playing download", "path", path, "err", err)
+			u.setStatus("[red]Error playing " + tview.Escape(filepath.Base(path)))
+			return
+		}
+		if err := updateDownload(path, func(d *download) bool {
+			d.Played = time.Now().UTC()
+			return true
+		}); err != nil {
+			slog.Error("error saving downloads list", "err", err)
+		}
+		u.pages.RemovePage("downloads")
+	})
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape || event.Rune() == 'q':
+			u.pages.RemovePage("downloads")
+			return nil
+		case event.Rune() == 'x' && list.GetCurrentItem() < len(downloads):
+			u.confirmDeleteDownload(downloads[list.GetCurrentItem()].Path)
+			return nil
+		}
+		return event
+	})
+	u.pages.AddPage("downloads", centered(list, 120, 24), true, true)
+}
+
+// confirmDeleteDownload asks before deleting a downloaded file.
+func (u *UI) confirmDeleteDownload(path string) {
+	modal := tview.NewModal().
+		SetText("Delete " + tview.Escape(filepath.Base(path)) + "?").
+		AddButtons([]string{"Delete", "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			u.pages.RemovePage("delete-download")
+			if label != "Delete" {
+				return
+			}
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				slog.Error("error deleting download", "path", path, "err", err)
+				u.setStatus("[red]Error deleting " + tview.Escape(filepath.Base(path)))
+				return
+			}
+			if err := updateDownload(path, func(*download) bool { return false }); err != nil {
+				slog.Error("error saving downloads list", "err", err)
+			}
+			u.pages.RemovePage("downloads")
+			u.showDownloads()
+			u.setStatus("Deleted " + tview.Escape(filepath.Base(path)))
+		})
+	u.pages.AddPage("delete-download", modal, true, true)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// minOverlap is the share of the smaller feed's items that must also be in
+// another feed for the two to be reported as overlapping.
+const minOverlap = 0.5
+
+// normalizeFeedURL reduces a feed URL to the parts that matter for telling
+// whether two subscriptions are the same feed: http and https, a leading
+// www. and a trailing slash don't.
+func normalizeFeedURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(rawURL)
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	path := strings.TrimSuffix(u.Path, "/")
+	normalized := host + path
+	if u.RawQuery != "" {
+		normalized += "?" + u.RawQuery
+	}
+	return normalized
+}
+
+// findSubscription returns the subscribed source matching any of the URLs,
+// such as a feed's address and the self link it declares.
+func findSubscription(sources []FeedSource, urls ...string) (FeedSource, bool) {
+	for _, source := range sources {
+		for _, u := range urls {
+			if u != "" && normalizeFeedURL(u) == normalizeFeedURL(source.URL) {
+				return source, true
+			}
+		}
+	}
+	return FeedSource{}, false
+}
+
+// runDuplicates lists subscriptions that point at the same feed, and pairs
+// of feeds that share most of their items.
+func runDuplicates() error {
+	sources, err := getFeedSources()
+	if err != nil {
+		return err
+	}
+
+	byURL := make(map[string][]FeedSource)
+	for _, source := range sources {
+		key := normalizeFeedURL(source.URL)
+		byURL[key] = append(byURL[key], source)
+	}
+	found := 0
+	for _, same := range byURL {
+		if len(same) < 2 {
+			continue
+		}
+		found++
+		fmt.Println("Same feed:")
+		for _, source := range same {
+			fmt.Printf("  %s\n", sourceLabel(source))
+		}
+	}
+
+	items, err := fetchFeeds(context.Background(), sources, printProgress)
+	if err != nil {
+		return err
+	}
+	links := make(map[string]map[string]bool)
+	for _, item := range items {
+		if item.Link == "" {
+			continue
+		}
+		if links[item.FeedURL] == nil {
+			links[item.FeedURL] = make(map[string]bool)
+		}
+		links[item.FeedURL][item.Link] = true
+	}
+
+	var feedURLs []string
+	for feedURL := range links {
+		feedURLs = append(feedURLs, feedURL)
+	}
+	sort.Strings(feedURLs)
+	names := make(map[string]FeedSource)
+	for _, source := range sources {
+		names[source.URL] = source
+	}
+	for i, a := range feedURLs {
+		for _, b := range feedURLs[i+1:] {
+			if normalizeFeedURL(a) == normalizeFeedURL(b) {
+				continue // already reported
+			}
+			shared := 0
+			for link := range links[a] {
+				if links[b][link] {
+					shared++
+				}
+			}
+			smaller := min(len(links[a]), len(links[b]))
+			if shared == 0 || float64(shared) < minOverlap*float64(smaller) {
+				continue
+			}
+			found++
+			fmt.Printf("%d shared items (%d%% of the smaller feed):\n  %s\n  %s\n",
+				shared, 100*shared/smaller, sourceLabel(names[a]), sourceLabel(names[b]))
+		}
+	}
+
+	if found == 0 {
+		fmt.Println("No duplicate feeds found.")
+	}
+	return nil
+}
+
+func sourceLabel(source FeedSource) string {
+	if source.Name == "" {
+		return source.URL
+	}
+	return source.Name + " (" + source.URL + ")"
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// itemDuration reads the play time of an item from itunes:duration or the
+// duration attribute of a media:content element.
+func itemDuration(item *gofeed.Item) time.Duration {
+	if item.ITunesExt != nil {
+		if d, ok := parseDuration(item.ITunesExt.Duration); ok {
+			return d
+		}
+	}
+	media := item.Extensions["media"]
+	contents := media["content"]
+	for _, group := range media["group"] {
+		contents = append(contents, group.Children["content"]...)
+	}
+	for _, content := range contents {
+		if d, ok := parseDuration(content.Attrs["duration"]); ok {
+			return d
+		}
+	}
+	return 0
+}
+
+// parseDuration parses the forms itunes:duration takes: seconds ("3725"),
+// "MM:SS" or "HH:MM:SS".
+func parseDuration(s string) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	var seconds float64
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		seconds = seconds*60 + n
+	}
+	if seconds <= 0 {
+		return 0, false
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
+
+// parseAge reads a max-age setting: a number of days ("30d"), weeks ("8w")
+// or hours ("12h"), or a bare number of days.
+func parseAge(s string) (time.Duration, bool) {
+	unit := 24 * time.Hour
+	switch {
+	case strings.HasSuffix(s, "d"):
+		s = strings.TrimSuffix(s, "d")
+	case strings.HasSuffix(s, "w"):
+		s, unit = strings.TrimSuffix(s, "w"), 7*24*time.Hour
+	case strings.HasSuffix(s, "h"):
+		s, unit = strings.TrimSuffix(s, "h"), time.Hour
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// formatDuration shows a play time compactly, e.g. "45m" or "3h05m".
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	if minutes < 1 {
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	}
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh%02dm", minutes/60, minutes%60)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	neturl "net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Elements whose text is never part of the readable article.
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "nav": true,
+	"header": true, "footer": true, "aside": true, "form": true,
+	"iframe": true, "svg": true, "button": true,
+}
+
+// Elements that start a new paragraph in the extracted text.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "blockquote": true,
+	"pre": true, "tr": true, "section": true, "article": true, "figcaption": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// extractArticle downloads a web page and returns its readable text, taken
+// from the <article> or <main> element when the page has one.
+func extractArticle(url string) (string, error) {
+	root, _, err := fetchArticle(url)
+	if err != nil {
+		return "", err
+	}
+	return nodeText(root), nil
+}
+
+// extractArticleLinks downloads a web page and returns the links in its
+// article content, resolved against the page's address.
+func extractArticleLinks(url string) ([]Link, error) {
+	root, base, err := fetchArticle(url)
+	if err != nil {
+		return nil, err
+	}
+	return collectLinks(base, root), nil
+}
+
+// fetchArticle downloads a page through the polite crawler and returns the
+// element holding its main content along with the page's URL.
+func fetchArticle(url string) (*html.Node, *neturl.URL, error) {
+	base, err := neturl.Parse(url)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body, err := articleCrawler.fetch(url)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching article %s: %v", url, err)
+	}
+
+	root, err := articleRoot(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing article %s: %v", url, err)
+	}
+	return root, base, nil
+}
+
+// articleRoot parses a page and returns the element holding its main content.
+func articleRoot(body []byte) (*html.Node, error) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	root := findElement(doc, "article")
+	if root == nil {
+		root = findElement(doc, "main")
+	}
+	if root == nil {
+		root = findElement(doc, "body")
+	}
+	if root == nil {
+		root = doc
+	}
+	return root, nil
+}
+
+// Link is a hyperlink found in an item's description or article.
+type Link struct {
+	Text string
+	URL  string
+}
+
+// descriptionLinks returns the links in an HTML fragment, which have
+// already been made absolute when the feed was fetched.
+func descriptionLinks(fragment string) []Link {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return nil
+	}
+
+	var links []Link
+	for _, n := range nodes {
+		links = append(links, collectLinks(nil, n)...)
+	}
+	return links
+}
+
+// collectLinks returns the http(s) links under n, in document order and
+// without duplicates.
+func collectLinks(base *neturl.URL, n *html.Node) []Link {
+	var links []Link
+	seen := make(map[string]bool)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			for _, attr := range n.Attr {
+				if attr.Key != "href" {
+					continue
+				}
+				href := resolveURL(base, attr.Val)
+				if (strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://")) && !seen[href] {
+					seen[href] = true
+					links = append(links, Link{Text: nodeText(n), URL: href})
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return links
+}
+
+// htmlToText converts an HTML fragment (such as an item description) into
+// plain text with one blank line between paragraphs.
+func htmlToText(fragment string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return fragment
+	}
+
+	var sb strings.Builder
+	for _, n := range nodes {
+		writeText(&sb, n)
+	}
+	return tidyParagraphs(sb.String())
+}
+
+func findElement(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	writeText(&sb, n)
+	return tidyParagraphs(sb.String())
+}
+
+func writeText(sb *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(n.Data)
+		return
+	case html.ElementNode:
+		if skippedElements[n.Data] {
+			return
+		}
+	}
+
+	block := n.Type == html.ElementNode && blockElements[n.Data]
+	if block {
+		sb.WriteString("\n\n")
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeText(sb, c)
+	}
+	if block {
+		sb.WriteString("\n\n")
+	}
+}
+
+// tidyParagraphs collapses whitespace inside paragraphs and drops empty ones.
+func tidyParagraphs(text string) string {
+	var paragraphs []string
+	for _, p := range strings.Split(text, "\n\n") {
+		p = strings.Join(strings.Fields(p), " ")
+		if p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	neturl "net/url"
+
+	"github.com/rivo/tview"
+)
+
+// actedFeed returns an item of the feed that W and Y act on: the feed
+// selected in the feed list when it has focus, otherwise the selected
+// item's feed.
+func (u *UI) actedFeed() (FeedItem, bool) {
+	if u.app.GetFocus() == u.feeds {
+		row, _ := u.feeds.GetSelection()
+		if row < 0 || row >= len(u.feedEntries) || u.feedEntries[row].name == "" || u.feedEntries[row].query != "" {
+			return FeedItem{}, false
+		}
+		for _, item := range u.items {
+			if item.FeedTitle == u.feedEntries[row].name {
+				return item, true
+			}
+		}
+		return FeedItem{}, false
+	}
+	index := u.selected()
+	if index < 0 {
+		return FeedItem{}, false
+	}
+	return u.items[index], true
+}
+
+// siteURL returns the homepage of an item's feed, or the root of the feed's
+// host when the feed doesn't link to one.
+func siteURL(item FeedItem) string {
+	if item.SiteURL != "" {
+		return item.SiteURL
+	}
+	u, err := neturl.Parse(item.FeedURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + "/"
+}
+
+// openSite opens the homepage of the selected feed.
+func (u *UI) openSite() {
+	item, ok := u.actedFeed()
+	if !ok {
+		u.setStatus("Select a feed first")
+		return
+	}
+	site := siteURL(item)
+	if site == "" {
+		u.setStatus("[red]" + tview.Escape(CleanString(item.FeedTitle)) + " has no homepage")
+		return
+	}
+	err := checkURL(site)
+	if err == nil {
+		err = openBrowser(site)
+	}
+	if errors.Is(err, errNoBrowser) {
+		if err := copyToClipboard(site); err != nil {
+			slog.Error("error copying site URL", "err", err)
+		}
+		u.setStatus("No browser in this session; copied " + tview.Escape(site))
+		return
+	}
+	if err != nil {
+		slog.Error("error opening site", "url", site, "err", err)
+		u.setStatus("[red]Error opening " + tview.Escape(site))
+	}
+}
+
+// copyFeedURL copies the selected feed's URL to the clipboard.
+func (u *UI) copyFeedURL() {
+	item, ok := u.actedFeed()
+	if !ok || item.FeedURL == "" {
+		u.setStatus("Select a feed first")
+		return
+	}
+	if err := copyToClipboard(item.FeedURL); err != nil {
+		slog.Error("error copying feed URL", "err", err)
+		u.setStatus("[red]Error copying: " + tview.Escape(err.Error()))
+		return
+	}
+	u.setStatus("Copied " + tview.Escape(item.FeedURL))
+}
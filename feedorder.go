@@ -0,0 +1,190 @@
+package main
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// feedTitles maps each feed URL to the title its items are listed under.
+func (u *UI) feedTitles() map[string]string {
+	titles := make(map[string]string)
+	for _, item := range u.items {
+		titles[item.FeedURL] = item.FeedTitle
+	}
+	return titles
+}
+
+// sourceTitle is the name a feed is listed under: the title its items
+// carry, or its name in feeds.csv when it has none yet.
+func sourceTitle(source FeedSource, titles map[string]string) string {
+	if name := titles[source.URL]; name != "" {
+		return name
+	}
+	if source.Name != "" {
+		return source.Name
+	}
+	return source.URL
+}
+
+// feedSourceIndex maps the names feeds are listed under to their index in
+// u.sources.
+func (u *UI) feedSourceIndex() map[string]int {
+	titles := u.feedTitles()
+	index := make(map[string]int)
+	for i, source := range u.sources {
+		if _, ok := source.query(); ok {
+			continue
+		}
+		index[sourceTitle(source, titles)] = i
+	}
+	return index
+}
+
+// lessFeed returns how feeds are ordered in the feed list and the feed
+// groups: pinned ones first, then by their order= in feeds.csv, then the
+// rest by name.
+func (u *UI) lessFeed() func(a, b string) bool {
+	index := u.feedSourceIndex()
+	rank := func(name string) FeedSource {
+		if i, ok := index[name]; ok {
+			return u.sources[i]
+		}
+		return FeedSource{}
+	}
+	return func(a, b string) bool {
+		ra, rb := rank(a), rank(b)
+		switch {
+		case ra.Pinned != rb.Pinned:
+			return ra.Pinned
+		case ra.Order != rb.Order && (ra.Order == 0 || rb.Order == 0):
+			return rb.Order == 0
+		case ra.Order != rb.Order:
+			return ra.Order < rb.Order
+		}
+		return lessName(a, b)
+	}
+}
+
+// selectedFeedSource returns the index in u.sources of the feed selected in
+// the feed list.
+func (u *UI) selectedFeedSource() (int, bool) {
+	row, _ := u.feeds.GetSelection()
+	if row < 0 || row >= len(u.feedEntries) || u.feedEntries[row].name == "" || u.feedEntries[row].query != "" {
+		u.setStatus("Select a feed first")
+		return 0, false
+	}
+	name := u.feedEntries[row].name
+	i, ok := u.feedSourceIndex()[name]
+	if !ok {
+		u.setStatus(tview.Escape(CleanString(name)) + " isn't in feeds.csv")
+		return 0, false
+	}
+	return i, true
+}
+
+// togglePinned pins the selected feed to the top of the feed list, or
+// unpins it.
+func (u *UI) togglePinned() {
+	i, ok := u.selectedFeedSource()
+	if !ok {
+		return
+	}
+	u.sources[i].Pinned = !u.sources[i].Pinned
+	row, _ := u.feeds.GetSelection()
+	name := u.feedEntries[row].name
+	if err := u.saveFeedOrder([]int{i}, name); err != nil {
+		return
+	}
+	if u.sources[i].Pinned {
+		u.setStatus("Pinned " + tview.Escape(CleanString(name)))
+	} else {
+		u.setStatus("Unpinned " + tview.Escape(CleanString(name)))
+	}
+}
+
+// moveFeed moves the selected feed up (delta -1) or down (delta 1) the feed
+// list. Every listed feed is then numbered with order=, so the order is kept
+// as feeds are added.
+func (u *UI) moveFeed(delta int) {
+	i, ok := u.selectedFeedSource()
+	if !ok {
+		return
+	}
+	row, _ := u.feeds.GetSelection()
+	other := row + delta
+	if other < 0 || other >= len(u.feedEntries) || u.feedEntries[other].name == "" || u.feedEntries[other].query != "" {
+		return
+	}
+	index := u.feedSourceIndex()
+	j, ok := index[u.feedEntries[other].name]
+	if !ok {
+		u.setStatus(tview.Escape(CleanString(u.feedEntries[other].name)) + " isn't in feeds.csv")
+		return
+	}
+	if u.sources[i].Pinned != u.sources[j].Pinned {
+		u.setStatus("Pinned feeds stay above the others; p to unpin")
+		return
+	}
+
+	name := u.feedEntries[row].name
+	u.feedEntries[row], u.feedEntries[other] = u.feedEntries[other], u.feedEntries[row]
+	var changed []int
+	order := 0
+	for _, entry := range u.feedEntries {
+		k, ok := index[entry.name]
+		if entry.query != "" || !ok {
+			continue
+		}
+		order++
+		if u.sources[k].Order != order {
+			u.sources[k].Order = order
+			changed = append(changed, k)
+		}
+	}
+	u.saveFeedOrder(changed, name)
+}
+
+// saveFeedOrder writes the pinned= and order= options of the changed
+// sources to feeds.csv, then lists the feeds again with the named one
+// selected.
+func (u *UI) saveFeedOrder(changed []int, selected string) error {
+	updates := make(map[string]FeedSource)
+	for _, i := range changed {
+		updates[u.sources[i].URL] = u.sources[i]
+	}
+	err := editFeedRecords(func(record []string) []string {
+		source, ok := updates[strings.TrimSpace(record[1])]
+		if !ok {
+			return nil
+		}
+		edited := record[:2:2]
+		for _, field := range record[2:] {
+			key, _, _ := strings.Cut(field, "=")
+			if key = strings.TrimSpace(key); key != "pinned" && key != "order" && key != "" {
+				edited = append(edited, field)
+			}
+		}
+		if source.Pinned {
+			edited = append(edited, "pinned=true")
+		}
+		if source.Order != 0 {
+			edited = append(edited, "order="+strconv.Itoa(source.Order))
+		}
+		return edited
+	})
+	u.renderFeeds()
+	for row, entry := range u.feedEntries {
+		if entry.name == selected && entry.query == "" {
+			u.feeds.Select(row, 0)
+		}
+	}
+	u.render()
+	if err != nil {
+		slog.Error("error saving feed order", "err", err)
+		u.setStatus("[red]Error saving feeds.csv: " + tview.Escape(err.Error()))
+	}
+	return err
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// tlsOptions are the per-feed TLS settings from feeds.csv, for feeds behind
+// a private CA or that require a client certificate.
+type tlsOptions struct {
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	// Insecure skips verifying the server's certificate altogether.
+	Insecure bool
+}
+
+var (
+	tlsClientsMutex sync.Mutex
+	tlsClients      = make(map[tlsOptions]*http.Client)
+)
+
+// feedClient returns the HTTP client for a feed: the shared one, or one set
+// up with the feed's TLS options, which is kept for feeds that share them.
+func feedClient(options tlsOptions) (*http.Client, error) {
+	if options == (tlsOptions{}) {
+		return httpClient, nil
+	}
+
+	tlsClientsMutex.Lock()
+	defer tlsClientsMutex.Unlock()
+	if client, ok := tlsClients[options]; ok {
+		return client, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: options.Insecure}
+	if options.CAFile != "" {
+		pem, err := os.ReadFile(expandPath(options.CAFile))
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA bundle: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", options.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if options.CertFile != "" || options.KeyFile != "" {
+		if options.CertFile == "" || options.KeyFile == "" {
+			return nil, fmt.Errorf("cert and key must be given together")
+		}
+		cert, err := tls.LoadX509KeyPair(expandPath(options.CertFile), expandPath(options.KeyFile))
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	base, ok := httpClient.Transport.(*http.Transport)
+	if !ok {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+	transport.TLSClientConfig = tlsConfig
+	client := &http.Client{Timeout: httpClient.Timeout, Transport: transport}
+	tlsClients[options] = client
+	return client, nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// fetchFeed downloads and parses a single feed, logging how long it took and
+// the HTTP status the server answered with. It also returns the number of
+// bytes transferred, which is the compressed size when the server gzips
+// the feed.
+func fetchFeed(ctx context.Context, fp *gofeed.Parser, source FeedSource) (*gofeed.Feed, int64, error) {
+	if _, _, ok := source.plugin(); ok {
+		return fetchPlugin(ctx, fp, source)
+	}
+	if _, _, ok := source.lemmy(); ok {
+		return fetchLemmy(ctx, source)
+	}
+	start := time.Now()
+	url := source.URL
+
+	client, err := feedClient(source.TLS)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if reset, limited := rateLimited(req.URL.Host); limited {
+		return nil, 0, fmt.Errorf("rate limited by %s until %s", req.URL.Host, reset.Local().Format("15:04"))
+	}
+	req.Header.Set("User-Agent", "newseum")
+	// Asking explicitly turns off the transport's transparent decompression,
+	// so the transferred size can be counted.
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		slog.Warn("fetch failed", "url", url, "err", err, "elapsed", time.Since(start))
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	recordRateLimit(req.URL.Host, resp)
+
+	slog.Info("fetched feed", "url", url, "status", resp.StatusCode, "elapsed", time.Since(start))
+	counter := &countingReader{r: resp.Body}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		io.Copy(io.Discard, counter)
+		return nil, counter.n, fmt.Errorf("http error: %s", resp.Status)
+	}
+
+	var body io.Reader = counter
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(counter)
+		if err != nil {
+			return nil, counter.n, fmt.Errorf("error decompressing feed: %v", err)
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	feed, err := fp.Parse(body)
+	if err != nil {
+		slog.Warn("parse failed", "url", url, "err", err)
+		return nil, counter.n, err
+	}
+
+	slog.Debug("parsed feed", "url", url, "type", feed.FeedType, "version", feed.FeedVersion,
+		"items", len(feed.Items), "bytes", counter.n, "elapsed", time.Since(start))
+	return feed, counter.n, nil
+}
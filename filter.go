@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// With more than debounceItems items, the filter is applied once typing
+// pauses for filterDelay rather than on every key.
+const (
+	debounceItems = 2000
+	filterDelay   = 100 * time.Millisecond
+)
+
+// showFilter puts a search box in place of the status line that narrows
+// the current tab as you type. Enter keeps the filter and Esc clears it.
+func (u *UI) showFilter() {
+	selected := u.selected()
+	input := tview.NewInputField().SetLabel("/").SetText(u.filter)
+	input.SetBackgroundColor(tcell.ColorDefault)
+	input.SetFieldBackgroundColor(tcell.ColorDefault)
+	var timer *time.Timer
+	input.SetChangedFunc(func(text string) {
+		if timer != nil {
+			timer.Stop()
+		}
+		if len(u.items) <= debounceItems {
+			u.applyFilter(text)
+			return
+		}
+		timer = time.AfterFunc(filterDelay, func() {
+			u.app.QueueUpdateDraw(func() {
+				if input.GetText() == text && u.filter != text {
+					u.applyFilter(text)
+				}
+			})
+		})
+	})
+	input.SetDoneFunc(func(key tcell.Key) {
+		if timer != nil {
+			timer.Stop()
+		}
+		if key != tcell.KeyEscape && u.filter != input.GetText() {
+			u.applyFilter(input.GetText())
+		}
+		u.root.RemoveItem(input)
+		u.root.AddItem(u.status, 1, 0, false)
+		u.app.SetFocus(u.table)
+		if key == tcell.KeyEscape || u.filter == "" {
+			u.filter = ""
+			u.render()
+			u.selectItem(selected)
+			u.setStatus("")
+			return
+		}
+		u.setStatus("Filter: " + tview.Escape(u.filter) + " (/ to change, Esc to clear)")
+	})
+
+	u.root.RemoveItem(u.status)
+	u.root.AddItem(input, 1, 0, true)
+	u.app.SetFocus(input)
+}
+
+// applyFilter shows the items matching text, best first. When text only
+// adds to the filter, the items already shown are narrowed down rather than
+// every item searched again.
+func (u *UI) applyFilter(text string) {
+	u.narrowing = true
+	u.filter = text
+	u.render()
+	u.narrowing = false
+	u.selectFirst()
+}
+
+// narrowsFilter reports whether every item matching filter also matches
+// previous: filter continues previous and doesn't change its words' quoting.
+func narrowsFilter(previous, filter string) bool {
+	return previous != "" && strings.HasPrefix(filter, previous) && !strings.Contains(filter, `"`)
+}
+
+// searchText returns the folded text of items[index] for the filter.
+func (u *UI) searchText(index int) searchText {
+	if text, ok := u.searchTexts[index]; ok {
+		return text
+	}
+	if u.searchTexts == nil {
+		u.searchTexts = make(map[int]searchText)
+	}
+	text := itemSearchText(u.items[index], true)
+	u.searchTexts[index] = text
+	return text
+}
+
+// clearFilter removes the filter, returning false if there is none.
+func (u *UI) clearFilter() bool {
+	if u.filter == "" {
+		return false
+	}
+	selected := u.selected()
+	u.filter = ""
+	u.render()
+	u.selectItem(selected)
+	u.setStatus("")
+	return true
+}
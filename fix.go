@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	"golang.org/x/term"
+)
+
+// commonFeedPaths are where sites usually serve their feed, tried on a broken
+// feed's host when its pages don't link one.
+var commonFeedPaths = []string{
+	"/feed", "/feed/", "/rss", "/rss.xml", "/atom.xml", "/feed.xml",
+	"/index.xml", "/feed.json", "/feeds/posts/default", "/?feed=rss2",
+}
+
+// feedCandidate is a working feed found in place of a broken one.
+type feedCandidate struct {
+	url   string
+	title string
+	items int
+}
+
+// runFix checks every feed and, for those that are broken or have moved,
+// looks for where the feed went: the permanent redirect, the feeds linked
+// from the old address and the site's homepage, and common feed paths. Each
+// replacement found is offered before feeds.csv is changed; without a
+// terminal they're only listed.
+func runFix() error {
+	sources, err := getFeedSources()
+	if err != nil {
+		return err
+	}
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	input := bufio.NewScanner(os.Stdin)
+
+	broken, fixed := 0, 0
+	for _, source := range sources {
+		if !source.webFeed() {
+			continue
+		}
+		c := checkFeed(source)
+		if c.failed == "" && len(c.warnings) == 0 {
+			continue
+		}
+		if c.failed == "" && !strings.HasPrefix(c.warnings[0], "moved permanently") {
+			continue
+		}
+		broken++
+		problem := c.failed
+		if problem == "" {
+			problem = c.warnings[0]
+		}
+		fmt.Printf("%s: %s\n", sourceLabel(source), problem)
+
+		candidates := findMovedFeed(source)
+		if len(candidates) == 0 {
+			fmt.Println("  No replacement found.")
+			continue
+		}
+		for i, candidate := range candidates {
+			fmt.Printf("  %d) %s (%s, %d items)\n", i+1, candidate.url, CleanString(candidate.title), candidate.items)
+		}
+		if !interactive {
+			continue
+		}
+
+		fmt.Printf("  Replace with [1-%d], or Enter to skip: ", len(candidates))
+		if !input.Scan() {
+			break
+		}
+		choice, err := strconv.Atoi(strings.TrimSpace(input.Text()))
+		if err != nil || choice < 1 || choice > len(candidates) {
+			fmt.Println("  Skipped.")
+			continue
+		}
+		if err := replaceFeedURL(source.URL, candidates[choice-1].url); err != nil {
+			return err
+		}
+		fixed++
+		fmt.Println("  Updated feeds.csv.")
+	}
+
+	switch {
+	case broken == 0:
+		fmt.Println("Every feed works.")
+	case interactive:
+		fmt.Printf("\n%d broken feeds, %d fixed\n", broken, fixed)
+	default:
+		fmt.Printf("\n%d broken feeds; run newseum fix in a terminal to replace them\n", broken)
+	}
+	return nil
+}
+
+// findMovedFeed looks for working feeds to replace a broken one with.
+func findMovedFeed(source FeedSource) []feedCandidate {
+	client, err := feedClient(source.TLS)
+	if err != nil {
+		return nil
+	}
+	old, err := neturl.Parse(source.URL)
+	if err != nil {
+		return nil
+	}
+	root := &neturl.URL{Scheme: old.Scheme, Host: old.Host, Path: "/"}
+
+	// The address the old URL ends up at, if it redirects, and the feeds
+	// that page and the homepage link come first.
+	var urls []string
+	for _, page := range []string{source.URL, root.String()} {
+		body, final, err := fetchPage(client, page)
+		if err != nil {
+			continue
+		}
+		if final.String() != source.URL {
+			urls = append(urls, final.String())
+		}
+		urls = append(urls, pageFeedLinks(body, final)...)
+	}
+	for _, path := range commonFeedPaths {
+		urls = append(urls, resolveURL(root, path))
+	}
+
+	var candidates []feedCandidate
+	seen := map[string]bool{normalizeFeedURL(source.URL): true}
+	for _, u := range urls {
+		key := normalizeFeedURL(u)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		body, final, err := fetchPage(client, u)
+		if err != nil {
+			continue
+		}
+		feed, err := gofeed.NewParser().Parse(bytes.NewReader(body))
+		if err != nil || len(feed.Items) == 0 {
+			continue
+		}
+		if final.String() != u && seen[normalizeFeedURL(final.String())] {
+			continue
+		}
+		seen[normalizeFeedURL(final.String())] = true
+		candidates = append(candidates, feedCandidate{url: final.String(), title: feed.Title, items: len(feed.Items)})
+	}
+	return candidates
+}
+
+// fetchPage downloads a page, returning it and the URL it was served from
+// after redirects.
+func fetchPage(client *http.Client, url string) ([]byte, *neturl.URL, error) {
+	req, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", "newseum")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, fmt.Errorf("http error: %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCheckBody))
+	return body, resp.Request.URL, err
+}
+
+// replaceFeedURL changes a feed's URL in feeds.csv, leaving every other line
+// as it was written.
+func replaceFeedURL(oldURL, newURL string) error {
+	return editFeedRecords(func(record []string) []string {
+		if strings.TrimSpace(record[1]) != oldURL {
+			return nil
+		}
+		record[1] = newURL
+		return record
+	})
+}
+
+// editFeedRecords rewrites the lines of feeds.csv and the files it includes
+// for which edit, given the line's columns, returns new ones, leaving the
+// others as they were written.
+func editFeedRecords(edit func(record []string) []string) error {
+	list, err := loadFeedList()
+	if err != nil {
+		return err
+	}
+	for _, path := range list.files {
+		if err := editFeedFile(path, edit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// editFeedFile applies editFeedRecords to one file, writing it only if a
+// line changed.
+func editFeedFile(path string, edit func(record []string) []string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %v", path, err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	changed := false
+	for i, line := range lines {
+		reader := csv.NewReader(strings.NewReader(line))
+		reader.FieldsPerRecord = -1
+		reader.Comment = '#'
+		record, err := reader.Read()
+		if err != nil || len(record) < 2 || strings.TrimSpace(record[0]) == "include" {
+			continue
+		}
+		if record = edit(record); record == nil {
+			continue
+		}
+		var sb strings.Builder
+		writer := csv.NewWriter(&sb)
+		writer.Write(record)
+		writer.Flush()
+		lines[i] = sb.String()
+		if !strings.HasSuffix(line, "\n") {
+			lines[i] = strings.TrimSuffix(lines[i], "\n")
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.WriteFile(tmp, []byte(strings.Join(lines, "")), 0o644); err != nil {
+		return fmt.Errorf("error writing %s: %v", path, err)
+	}
+	return os.Rename(tmp, path)
+}
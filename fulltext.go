@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fullTextRetention is how long the text of an article that wasn't archived
+// stays in the full-text index.
+const fullTextRetention = 90 * 24 * time.Hour
+
+// fullTextEntry is the folded text of one downloaded article.
+type fullTextEntry struct {
+	Text string    `json:"text"`
+	Time time.Time `json:"time"`
+	// Keep is set for archived articles, which stay indexed for as long as
+	// they're archived.
+	Keep bool `json:"keep,omitempty"`
+}
+
+// fullText indexes the text of prefetched and archived articles by link, so
+// searches find items by words that only appear deep in the article and not
+// in the feed's description. It is read from the cache directory on first
+// use.
+var fullText = struct {
+	sync.Mutex
+	loaded  bool
+	entries map[string]fullTextEntry
+}{}
+
+func fullTextPath() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "fulltext.json"), nil
+}
+
+// loadFullText reads the index if it hasn't been yet. The caller holds the
+// lock.
+func loadFullText() {
+	if fullText.loaded {
+		return
+	}
+	fullText.loaded = true
+	fullText.entries = make(map[string]fullTextEntry)
+	path, err := fullTextPath()
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &fullText.entries); err != nil {
+		slog.Warn("error reading full-text index", "path", path, "err", err)
+	}
+}
+
+// indexArticle adds an article's text to the index. keep marks archived
+// articles, which aren't dropped as the index ages.
+func indexArticle(link, text string, keep bool) {
+	if link == "" || strings.TrimSpace(text) == "" {
+		return
+	}
+	fullText.Lock()
+	defer fullText.Unlock()
+	loadFullText()
+	keep = keep || fullText.entries[link].Keep
+	fullText.entries[link] = fullTextEntry{Text: searchable(text), Time: time.Now().UTC(), Keep: keep}
+}
+
+// articleFullText returns the folded text indexed for a link, or "".
+func articleFullText(link string) string {
+	if link == "" {
+		return ""
+	}
+	fullText.Lock()
+	defer fullText.Unlock()
+	loadFullText()
+	return fullText.entries[link].Text
+}
+
+// saveFullText writes the index, dropping old entries that aren't kept.
+func saveFullText() error {
+	fullText.Lock()
+	defer fullText.Unlock()
+	if !fullText.loaded {
+		return nil
+	}
+	cutoff := time.Now().Add(-fullTextRetention)
+	for link, entry := range fullText.entries {
+		if !entry.Keep && entry.Time.Before(cutoff) {
+			delete(fullText.entries, link)
+		}
+	}
+
+	path, err := fullTextPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(fullText.entries)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// searchable folds text for searching and puts it on one line with single
+// spaces, so quoted phrases match across line breaks.
+func searchable(text string) string {
+	return strings.Join(strings.Fields(foldText(text)), " ")
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The gpodder.net API (also served by opodsync and Nextcloud's gPodder
+// Sync) keeps podcast subscriptions and played episodes in step with
+// podcatchers such as AntennaPod.
+
+// gpodderTimeFormat is the timestamp format of episode actions, in UTC.
+const gpodderTimeFormat = "2006-01-02T15:04:05"
+
+// gpodderAction is an episode action: an episode being downloaded or
+// played, with the position reached in seconds.
+type gpodderAction struct {
+	Podcast   string `json:"podcast"`
+	Episode   string `json:"episode"`
+	Device    string `json:"device,omitempty"`
+	Action    string `json:"action"`
+	Timestamp string `json:"timestamp"`
+	Started   *int   `json:"started,omitempty"`
+	Position  *int   `json:"position,omitempty"`
+	Total     *int   `json:"total,omitempty"`
+}
+
+// gpodderSync remembers the timestamps the server returned, so each sync
+// asks only for changes since the last, and the feeds already uploaded.
+type gpodderSync struct {
+	Subscriptions int64    `json:"subscriptions"`
+	Episodes      int64    `json:"episodes"`
+	Uploaded      []string `json:"uploaded"`
+}
+
+// gpodderMutex serializes syncs and uploads.
+var gpodderMutex sync.Mutex
+
+func gpodderEnabled() bool {
+	return config.GPodderUser != ""
+}
+
+func gpodderStatePath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gpodder.json"), nil
+}
+
+func loadGPodderSync() gpodderSync {
+	var state gpodderSync
+	if path, err := gpodderStatePath(); err == nil {
+		if data, err := os.ReadFile(path); err == nil {
+			json.Unmarshal(data, &state)
+		}
+	}
+	return state
+}
+
+func saveGPodderSync(state gpodderSync) error {
+	path, err := gpodderStatePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// gpodderDevice is the device newseum registers as.
+func gpodderDevice() string {
+	if config.GPodderDevice != "" {
+		return config.GPodderDevice
+	}
+	return "newseum"
+}
+
+// gpodderRequest calls an API endpoint with basic authentication, sending
+// body as JSON if it isn't nil and decoding the reply into result.
+func gpodderRequest(method, path string, query url.Values, body, result any) error {
+	base := strings.TrimSuffix(config.GPodderURL, "/")
+	if base == "" {
+		base = "https://gpodder.net"
+	}
+	endpoint := base + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(config.GPodderUser, config.GPodderPassword)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("gpodder: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("gpodder: %s %s: %s", method, path, resp.Status)
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("gpodder: invalid reply from %s: %v", path, err)
+	}
+	return nil
+}
+
+// gpodderSyncResult is what a sync found on the server.
+type gpodderSyncResult struct {
+	// Added are the podcasts subscribed to elsewhere that feeds.csv lacks.
+	Added []string
+	// Played are the episode URLs played to the end elsewhere.
+	Played map[string]bool
+}
+
+// syncGPodder uploads the podcast feeds among podcasts that the server
+// doesn't know of and downloads the subscriptions and episode actions since
+// the last sync. Unsubscribing isn't synced; feeds.csv is only added to.
+func syncGPodder(podcasts []string, subscribed map[string]bool) (gpodderSyncResult, error) {
+	gpodderMutex.Lock()
+	defer gpodderMutex.Unlock()
+
+	result := gpodderSyncResult{Played: make(map[string]bool)}
+	state := loadGPodderSync()
+	user, device := url.PathEscape(config.GPodderUser), url.PathEscape(gpodderDevice())
+
+	var changes struct {
+		Add       []string `json:"add"`
+		Remove    []string `json:"remove"`
+		Timestamp int64    `json:"timestamp"`
+	}
+	path := "/api/2/subscriptions/" + user + "/" + device + ".json"
+	if err := gpodderRequest("GET", path, url.Values{"since": {fmt.Sprint(state.Subscriptions)}}, nil, &changes); err != nil {
+		return result, err
+	}
+	known := make(map[string]bool)
+	for _, feed := range state.Uploaded {
+		known[feed] = true
+	}
+	for _, feed := range changes.Add {
+		known[feed] = true
+		if !subscribed[feed] {
+			result.Added = append(result.Added, feed)
+		}
+	}
+	state.Subscriptions = changes.Timestamp
+
+	var upload []string
+	for _, feed := range podcasts {
+		if !known[feed] {
+			upload = append(upload, feed)
+		}
+	}
+	if len(upload) > 0 {
+		var reply struct {
+			Timestamp int64 `json:"timestamp"`
+		}
+		body := map[string][]string{"add": upload, "remove": {}}
+		if err := gpodderRequest("POST", path, nil, body, &reply); err != nil {
+			return result, err
+		}
+		state.Subscriptions = reply.Timestamp
+		state.Uploaded = append(state.Uploaded, upload...)
+	}
+
+	var actions struct {
+		Actions   []gpodderAction `json:"actions"`
+		Timestamp int64           `json:"timestamp"`
+	}
+	query := url.Values{"since": {fmt.Sprint(state.Episodes)}, "aggregated": {"true"}}
+	if err := gpodderRequest("GET", "/api/2/episodes/"+user+".json", query, nil, &actions); err != nil {
+		return result, err
+	}
+	for _, action := range actions.Actions {
+		if action.Action == "play" && action.Device != gpodderDevice() && playedToEnd(action) {
+			result.Played[action.Episode] = true
+		}
+	}
+	state.Episodes = actions.Timestamp
+
+	if err := saveGPodderSync(state); err != nil {
+		slog.Warn("error saving gpodder sync state", "err", err)
+	}
+	return result, nil
+}
+
+// playedToEnd reports whether a play action reached the end of the
+// episode, give or take the credits.
+func playedToEnd(action gpodderAction) bool {
+	if action.Position == nil || action.Total == nil || *action.Total <= 0 {
+		return false
+	}
+	return *action.Position >= *action.Total-30
+}
+
+// uploadGPodderAction reports that an episode of a podcast was played to
+// the end or downloaded.
+func uploadGPodderAction(kind string, item FeedItem, episode string) {
+	if !gpodderEnabled() || episode == "" {
+		return
+	}
+	action := gpodderAction{
+		Podcast:   item.FeedURL,
+		Episode:   episode,
+		Device:    gpodderDevice(),
+		Action:    kind,
+		Timestamp: time.Now().UTC().Format(gpodderTimeFormat),
+	}
+	if kind == "play" {
+		// newseum doesn't see the player's position, so a played episode
+		// is reported as played through.
+		total := int(item.Duration / time.Second)
+		if total <= 0 {
+			total = 1
+		}
+		start := 0
+		action.Started, action.Position, action.Total = &start, &total, &total
+	}
+	goSafe(func() {
+		gpodderMutex.Lock()
+		defer gpodderMutex.Unlock()
+		path := "/api/2/episodes/" + url.PathEscape(config.GPodderUser) + ".json"
+		if err := gpodderRequest("POST", path, nil, []gpodderAction{action}, nil); err != nil {
+			slog.Error("error uploading episode action", "episode", episode, "err", err)
+		}
+	})
+}
+
+// syncPodcasts syncs with the gpodder server after a refresh: podcasts
+// subscribed to elsewhere are added to feeds.csv, and episodes played
+// elsewhere are marked read.
+func (u *UI) syncPodcasts() {
+	if !gpodderEnabled() {
+		return
+	}
+	subscribed := make(map[string]bool)
+	for _, source := range u.sources {
+		subscribed[source.URL] = true
+	}
+	seen := make(map[string]bool)
+	var podcasts []string
+	for _, item := range u.items {
+		if item.AudioURL != "" && subscribed[item.FeedURL] && !seen[item.FeedURL] {
+			seen[item.FeedURL] = true
+			podcasts = append(podcasts, item.FeedURL)
+		}
+	}
+
+	goSafe(func() {
+		result, err := syncGPodder(podcasts, subscribed)
+		if err != nil {
+			slog.Error("error syncing with gpodder", "err", err)
+			return
+		}
+		added := 0
+		if len(result.Added) > 0 && u.backend == nil {
+			var sources []FeedSource
+			for _, feed := range result.Added {
+				sources = append(sources, FeedSource{URL: feed, Category: "Podcasts"})
+			}
+			if added, err = appendFeedSources(sources); err != nil {
+				slog.Error("error adding podcasts from gpodder", "err", err)
+			}
+		}
+		u.app.QueueUpdateDraw(func() {
+			for i, item := range u.items {
+				if !result.Played[item.AudioURL] || item.Read {
+					continue
+				}
+				// Not markRead, which would report the play back
+				u.items[i].Read = true
+				u.refreshRow(i)
+				if u.backend == nil {
+					u.saveState(u.items[i])
+					continue
+				}
+				read := u.items[i]
+				goWrite(func() {
+					if err := u.backend.MarkRead(read, true); err != nil {
+						slog.Error("error syncing read state", "err", err)
+					}
+				})
+			}
+			if added > 0 {
+				sources, err := getFeedSources()
+				if err != nil {
+					slog.Error("error reloading feeds.csv", "err", err)
+					u.setStatus(fmt.Sprintf("[red]Added %s from gpodder; error reloading feeds.csv", plural(added, "podcast")))
+					return
+				}
+				u.sources = sources
+				u.setStatus(fmt.Sprintf("Added %s from gpodder; R to fetch", plural(added, "podcast")))
+			}
+		})
+	})
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	greaderReadTag    = "user/-/state/com.google/read"
+	greaderStarredTag = "user/-/state/com.google/starred"
+
+	// greaderMaxItems caps how much of the reading list is downloaded.
+	greaderMaxItems = 1000
+)
+
+// GReaderBackend talks the Google Reader API spoken by FreshRSS,
+// TheOldReader, BazQux, Inoreader and others. baseURL is the API root, e.g.
+// https://example.com/api/greader.php for FreshRSS.
+type GReaderBackend struct {
+	baseURL string
+	auth    string
+
+	mutex sync.Mutex // guards token
+	token string
+}
+
+func newGReaderBackend(baseURL, user, password string) (*GReaderBackend, error) {
+	if baseURL == "" || user == "" {
+		return nil, fmt.Errorf("the greader backend needs backend-url and backend-user")
+	}
+
+	b := &GReaderBackend{baseURL: strings.TrimSuffix(baseURL, "/")}
+
+	resp, err := httpClient.PostForm(b.baseURL+"/accounts/ClientLogin", url.Values{
+		"Email":  {user},
+		"Passwd": {password},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error logging in to %s: %v", baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error logging in to %s: %v", baseURL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error logging in to %s: %s", baseURL, resp.Status)
+	}
+
+	for _, line := range strings.Split(string(body), "\n") {
+		if auth, found := strings.CutPrefix(line, "Auth="); found {
+			b.auth = strings.TrimSpace(auth)
+		}
+	}
+	if b.auth == "" {
+		return nil, fmt.Errorf("error logging in to %s: no auth token in response", baseURL)
+	}
+	return b, nil
+}
+
+func (b *GReaderBackend) request(ctx context.Context, method, path string, form url.Values) ([]byte, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "GoogleLogin auth="+b.auth)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	return data, nil
+}
+
+type greaderItem struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Published  int64    `json:"published"`
+	Categories []string `json:"categories"`
+	Canonical  []struct {
+		Href string `json:"href"`
+	} `json:"canonical"`
+	Alternate []struct {
+		Href string `json:"href"`
+	} `json:"alternate"`
+	Summary struct {
+		Content string `json:"content"`
+	} `json:"summary"`
+	Content struct {
+		Content string `json:"content"`
+	} `json:"content"`
+	Enclosure []struct {
+		Href   string `json:"href"`
+		Type   string `json:"type"`
+		Length string `json:"length"`
+	} `json:"enclosure"`
+	Origin struct {
+		Title    string `json:"title"`
+		StreamID string `json:"streamId"`
+		HTMLURL  string `json:"htmlUrl"`
+	} `json:"origin"`
+}
+
+func (b *GReaderBackend) Fetch(ctx context.Context) ([]FeedItem, error) {
+	var items []FeedItem
+	continuation := ""
+	for len(items) < greaderMaxItems {
+		query := url.Values{"output": {"json"}, "n": {"250"}}
+		if continuation != "" {
+			query.Set("c", continuation)
+		}
+		data, err := b.request(ctx, "GET", "/reader/api/0/stream/contents/user/-/state/com.google/reading-list?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching reading list: %v", err)
+		}
+
+		var stream struct {
+			Items        []greaderItem `json:"items"`
+			Continuation string        `json:"continuation"`
+		}
+		if err := json.Unmarshal(data, &stream); err != nil {
+			return nil, fmt.Errorf("error parsing reading list: %v", err)
+		}
+
+		for _, gi := range stream.Items {
+			items = append(items, gi.feedItem())
+		}
+		if stream.Continuation == "" || len(stream.Items) == 0 {
+			break
+		}
+		continuation = stream.Continuation
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Date.After(items[j].Date)
+	})
+	return items, nil
+}
+
+func (gi greaderItem) feedItem() FeedItem {
+	item := FeedItem{
+		ID:          gi.ID,
+		Title:       gi.Title,
+		Date:        time.Unix(gi.Published, 0).UTC(),
+		FeedTitle:   gi.Origin.Title,
+		FeedURL:     strings.TrimPrefix(gi.Origin.StreamID, "feed/"),
+		SiteURL:     gi.Origin.HTMLURL,
+		Description: newItemBody(gi.Summary.Content),
+	}
+	if gi.Content.Content != "" {
+		item.Description = newItemBody(gi.Content.Content)
+	}
+	if len(gi.Canonical) > 0 {
+		item.Link = cleanLink(gi.Canonical[0].Href)
+	} else if len(gi.Alternate) > 0 {
+		item.Link = cleanLink(gi.Alternate[0].Href)
+	}
+	for _, enclosure := range gi.Enclosure {
+		if item.AudioURL == "" && strings.HasPrefix(enclosure.Type, "audio/") {
+			item.AudioURL = enclosure.Href
+		}
+		length, _ := strconv.ParseInt(enclosure.Length, 10, 64)
+		item.Enclosures = append(item.Enclosures, Enclosure{URL: enclosure.Href, Type: enclosure.Type, Length: length})
+	}
+	for _, category := range gi.Categories {
+		switch {
+		case strings.HasSuffix(category, "/state/com.google/read"):
+			item.Read = true
+		case strings.HasSuffix(category, "/state/com.google/starred"):
+			item.Starred = true
+		case strings.Contains(category, "/label/") && item.Category == "":
+			item.Category = category[strings.Index(category, "/label/")+len("/label/"):]
+		}
+	}
+	return item
+}
+
+// editTag adds or removes a state tag on an item. Edits need a short-lived
+// token, fetched on first use and refreshed once if the server rejects it.
+func (b *GReaderBackend) editTag(item FeedItem, tag string, add bool) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if b.token == "" {
+			token, err := b.request(context.Background(), "GET", "/reader/api/0/token", nil)
+			if err != nil {
+				return fmt.Errorf("error fetching edit token: %v", err)
+			}
+			b.token = strings.TrimSpace(string(token))
+		}
+
+		form := url.Values{"i": {item.ID}, "T": {b.token}}
+		if add {
+			form.Set("a", tag)
+		} else {
+			form.Set("r", tag)
+		}
+		if _, err := b.request(context.Background(), "POST", "/reader/api/0/edit-tag", form); err == nil {
+			return nil
+		} else if attempt == 1 {
+			return fmt.Errorf("error updating %s: %v", item.Title, err)
+		}
+		b.token = ""
+	}
+	return nil
+}
+
+func (b *GReaderBackend) MarkRead(item FeedItem, read bool) error {
+	return b.editTag(item, greaderReadTag, read)
+}
+
+func (b *GReaderBackend) SetStarred(item FeedItem, starred bool) error {
+	return b.editTag(item, greaderStarredTag, starred)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// maxHistory is how many items the reading history keeps, and
+// historyRetention how long it keeps one after it was last opened.
+const (
+	maxHistory       = 5000
+	historyRetention = 365 * 24 * time.Hour
+)
+
+// historyEntry is an item that was opened, with when it was last opened and
+// how many times.
+type historyEntry struct {
+	Key    string    `json:"key"`
+	Title  string    `json:"title"`
+	Link   string    `json:"link,omitempty"`
+	Feed   string    `json:"feed,omitempty"`
+	Opened time.Time `json:"opened"`
+	Count  int       `json:"count"`
+}
+
+// historyMutex serializes changes to the history within the process; a lock
+// file does between processes, such as the daemon's web page.
+var historyMutex sync.Mutex
+
+func historyPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.json"), nil
+}
+
+// loadHistory reads the history, most recently opened first.
+func loadHistory() []historyEntry {
+	path, err := historyPath()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var entries []historyEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		slog.Warn("error reading history", "path", path, "err", err)
+	}
+	return entries
+}
+
+// recordOpen moves an item to the top of the history, counting the open.
+func recordOpen(item FeedItem) error {
+	path, err := historyPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return fmt.Errorf("error locking history: %v", err)
+	}
+	defer unlock()
+
+	now := time.Now().UTC()
+	key := itemKey(item)
+	entry := historyEntry{Key: key, Title: CleanString(item.Title), Link: item.Link, Feed: CleanString(item.FeedTitle)}
+	entries := []historyEntry{entry}
+	cutoff := now.Add(-historyRetention)
+	for _, e := range loadHistory() {
+		switch {
+		case e.Key == key:
+			entries[0].Count = e.Count
+		case e.Opened.After(cutoff) && len(entries) < maxHistory:
+			entries = append(entries, e)
+		}
+	}
+	entries[0].Opened = now
+	entries[0].Count++
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// recordOpened adds an item to the history in the background.
+func (u *UI) recordOpened(item FeedItem) {
+	goWrite(func() {
+		if err := recordOpen(item); err != nil {
+			slog.Warn("error saving history", "err", err)
+		}
+	})
+}
+
+// showHistory lists the items opened, most recent first, with a search box
+// that narrows them by title or feed as you type. Enter opens the item again.
+func (u *UI) showHistory() {
+	historyMutex.Lock()
+	entries := loadHistory()
+	historyMutex.Unlock()
+
+	input := tview.NewInputField().SetLabel("Search: ")
+	input.SetFieldBackgroundColor(tcell.ColorDefault)
+	input.SetBackgroundColor(tcell.ColorDefault)
+	list := tview.NewList().ShowSecondaryText(true)
+	list.SetBackgroundColor(tcell.ColorDefault)
+
+	var shown []historyEntry
+	fill := func(query string) {
+		list.Clear()
+		shown = shown[:0]
+		q := parseQuery(query)
+		for _, e := range entries {
+			if query != "" {
+				if _, ok := q.score(searchText{title: foldText(e.Title), feed: foldText(e.Feed)}); !ok {
+					continue
+				}
+			}
+			shown = append(shown, e)
+			opens := ""
+			if e.Count > 1 {
+				opens = fmt.Sprintf(" · opened %d times", e.Count)
+			}
+			list.AddItem(tview.Escape(e.Title+" — "+e.Feed),
+				tview.Escape(formatDate(e.Opened, u.now)+opens+" · "+e.Link), 0, nil)
+		}
+		if len(entries) == 0 {
+			list.AddItem("Nothing opened yet", "", 0, nil)
+		} else if len(shown) == 0 {
+			list.AddItem("Nothing matches", "", 0, nil)
+		}
+	}
+	fill("")
+	input.SetChangedFunc(fill)
+
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			u.pages.RemovePage("history")
+			return nil
+		case tcell.KeyUp, tcell.KeyDown, tcell.KeyPgUp, tcell.KeyPgDn:
+			list.InputHandler()(event, nil)
+			return nil
+		case tcell.KeyEnter:
+			i := list.GetCurrentItem()
+			if i >= len(shown) || shown[i].Link == "" {
+				return nil
+			}
+			e := shown[i]
+			if err := openURL(desktopLink(e.Link)); err != nil {
+				slog.Error("error opening item", "url", e.Link, "err", err)
+				u.setStatus("[red]Error opening " + tview.Escape(e.Link))
+				return nil
+			}
+			u.recordOpened(FeedItem{ID: e.Key, Title: e.Title, Link: e.Link, FeedTitle: e.Feed})
+			u.pages.RemovePage("history")
+			return nil
+		}
+		return event
+	})
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(input, 1, 0, true).
+		AddItem(list, 0, 1, false)
+	layout.SetBorder(true).SetTitle(" History (type to search, Enter to open, Esc to close) ")
+	layout.SetBackgroundColor(tcell.ColorDefault)
+	u.pages.AddPage("history", centered(layout, 120, 24), true, true)
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const importUsage = `usage: newseum import <format> [file]
+
+Formats:
+  newsboat     newsboat urls file (default ~/.newsboat/urls or ~/.config/newsboat/urls)
+  liferea      Liferea feed list (default ~/.config/liferea/feedlist.opml)
+  thunderbird  Thunderbird OPML export or a profile's feeds.json
+  opml         any OPML file`
+
+// runImport adds the subscriptions from another reader to feeds.csv,
+// skipping URLs that are already there.
+func runImport(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("%s", importUsage)
+	}
+
+	format := args[0]
+	path := ""
+	if len(args) == 2 {
+		path = args[1]
+	} else {
+		path = defaultImportPath(format)
+	}
+	if path == "" {
+		return fmt.Errorf("%s", importUsage)
+	}
+
+	var sources []FeedSource
+	var err error
+	switch format {
+	case "newsboat":
+		sources, err = importNewsboat(path)
+	case "liferea", "opml":
+		sources, err = importOPML(path)
+	case "thunderbird":
+		if strings.HasSuffix(path, ".json") {
+			sources, err = importThunderbirdJSON(path)
+		} else {
+			sources, err = importOPML(path)
+		}
+	default:
+		return fmt.Errorf("unknown import format %q\n%s", format, importUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	added, err := appendFeedSources(sources)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d of %d feeds from %s\n", added, len(sources), path)
+	return nil
+}
+
+func defaultImportPath(format string) string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	var candidates []string
+	switch format {
+	case "newsboat":
+		candidates = []string{
+			filepath.Join(homeDir, ".newsboat", "urls"),
+			filepath.Join(homeDir, ".config", "newsboat", "urls"),
+		}
+	case "liferea":
+		candidates = []string{filepath.Join(homeDir, ".config", "liferea", "feedlist.opml")}
+	}
+
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// importNewsboat reads a newsboat urls file. Each line is a URL followed by
+// quoted tags; a tag starting with ~ renames the feed and the first plain
+// tag becomes its category. Query, exec and filter feeds have no newseum
+// equivalent and are skipped.
+func importNewsboat(path string) ([]FeedSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening newsboat urls file: %v", err)
+	}
+	defer file.Close()
+
+	var sources []FeedSource
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := splitNewsboatLine(line)
+		url := fields[0]
+		if strings.HasPrefix(url, "query:") || strings.HasPrefix(url, "exec:") || strings.HasPrefix(url, "filter:") {
+			fmt.Printf("Skipping unsupported newsboat feed %s\n", url)
+			continue
+		}
+
+		source := FeedSource{URL: url}
+		for _, tag := range fields[1:] {
+			if strings.HasPrefix(tag, "~") {
+				source.Name = strings.TrimPrefix(tag, "~")
+			} else if !strings.HasPrefix(tag, "!") && source.Category == "" {
+				source.Category = tag
+			}
+		}
+		sources = append(sources, source)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading newsboat urls file: %v", err)
+	}
+	return sources, nil
+}
+
+// splitNewsboatLine splits a urls line on spaces, keeping "quoted tags" whole.
+func splitNewsboatLine(line string) []string {
+	var fields []string
+	var current strings.Builder
+	quoted := false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case (r == ' ' || r == '\t') && !quoted:
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr"`
+	XMLURL   string        `xml:"xmlUrl,attr"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+type opmlDocument struct {
+	Outlines []opmlOutline `xml:"body>outline"`
+}
+
+// importOPML reads every outline with an xmlUrl, at any nesting depth. The
+// folder outline a feed sits in becomes its category.
+func importOPML(path string) ([]FeedSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening OPML file: %v", err)
+	}
+
+	var doc opmlDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing OPML file: %v", err)
+	}
+
+	var sources []FeedSource
+	var walk func(outlines []opmlOutline, category string)
+	walk = func(outlines []opmlOutline, category string) {
+		for _, o := range outlines {
+			name := o.Title
+			if name == "" {
+				name = o.Text
+			}
+			if o.XMLURL != "" {
+				sources = append(sources, FeedSource{Name: name, URL: o.XMLURL, Category: category})
+			}
+			walk(o.Outlines, name)
+		}
+	}
+	walk(doc.Outlines, "")
+	return sources, nil
+}
+
+// importThunderbirdJSON reads the feeds.json kept in each Thunderbird
+// "Blogs & News Feeds" account directory.
+func importThunderbirdJSON(path string) ([]FeedSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening Thunderbird feeds file: %v", err)
+	}
+
+	var feeds []struct {
+		URL        string `json:"url"`
+		Title      string `json:"title"`
+		DestFolder string `json:"destFolder"`
+	}
+	if err := json.Unmarshal(data, &feeds); err != nil {
+		return nil, fmt.Errorf("error parsing Thunderbird feeds file: %v", err)
+	}
+
+	var sources []FeedSource
+	for _, feed := range feeds {
+		if feed.URL == "" {
+			continue
+		}
+		// destFolder is a folder URI such as mailbox://nobody@Feeds/Tech
+		category := ""
+		if _, path, found := strings.Cut(feed.DestFolder, "://"); found {
+			if i := strings.LastIndex(path, "/"); i >= 0 {
+				category, _ = url.PathUnescape(path[i+1:])
+			}
+		}
+		sources = append(sources, FeedSource{Name: feed.Title, URL: feed.URL, Category: category})
+	}
+	return sources, nil
+}
+
+// appendFeedSources adds sources whose URL isn't subscribed yet to the end
+// of feeds.csv, creating it if needed, and returns how many were added.
+func appendFeedSources(sources []FeedSource) (int, error) {
+	path, err := feedsPath()
+	if err != nil {
+		return 0, err
+	}
+
+	existing := make(map[string]bool)
+	if current, err := getFeedSources(); err == nil {
+		for _, source := range current {
+			existing[normalizeFeedURL(source.URL)] = true
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, fmt.Errorf("error creating config directory: %v", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return 0, fmt.Errorf("error opening file %s: %v", path, err)
+	}
+	defer file.Close()
+	// Start on a new line after a last line without one, such as a comment
+	if data, err := os.ReadFile(path); err == nil && len(data) > 0 && data[len(data)-1] != '\n' {
+		if _, err := file.WriteString("\n"); err != nil {
+			return 0, fmt.Errorf("error writing %s: %v", path, err)
+		}
+	}
+
+	writer := csv.NewWriter(file)
+	added := 0
+	for _, source := range sources {
+		key := normalizeFeedURL(source.URL)
+		if existing[key] {
+			continue
+		}
+		existing[key] = true
+		if err := writer.Write(source.record()); err != nil {
+			return added, fmt.Errorf("error writing %s: %v", path, err)
+		}
+		added++
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return added, fmt.Errorf("error writing %s: %v", path, err)
+	}
+	return added, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+func itemCachePath() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "items.json"), nil
+}
+
+// loadItemCache returns the items saved by the last fetch, grouped by feed
+// URL, so feeds that can't be reached still show their last known items.
+func loadItemCache() map[string][]FeedItem {
+	cached := make(map[string][]FeedItem)
+	path, err := itemCachePath()
+	if err != nil {
+		return cached
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cached
+	}
+
+	var items []FeedItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return cached
+	}
+	for _, item := range items {
+		cached[item.FeedURL] = append(cached[item.FeedURL], item)
+	}
+	return cached
+}
+
+// saveItemCache replaces the saved items with the result of a fetch.
+func saveItemCache(items []FeedItem) error {
+	path, err := itemCachePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keyringLookup reads a secret from the login keychain. Store one with:
+//
+//	security add-generic-password -s newseum -a NAME -w
+func keyringLookup(name string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", "newseum", "-a", name, "-w").Output()
+	if err != nil {
+		return "", fmt.Errorf("error reading %q from the keychain: %v", name, err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
@@ -0,0 +1,21 @@
+//go:build !windows && !darwin
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keyringLookup reads a secret from the Secret Service (GNOME Keyring,
+// KWallet) through secret-tool. Store one with:
+//
+//	secret-tool store --label=newseum service newseum account NAME
+func keyringLookup(name string) (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", "newseum", "account", name).Output()
+	if err != nil {
+		return "", fmt.Errorf("error reading %q from the keyring: %v", name, err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
@@ -0,0 +1,9 @@
+package main
+
+import "fmt"
+
+// keyringLookup is not available on Windows, whose Credential Manager has
+// no command to print a secret; cmd: with a password manager's CLI works.
+func keyringLookup(name string) (string, error) {
+	return "", fmt.Errorf("keyring: is not supported on Windows; use cmd: instead")
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// normalizeLanguage reduces a language tag such as "en-US" or "pt_BR" to its
+// primary language, "en" or "pt", so a feed's regional variant doesn't split
+// the filter.
+func normalizeLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// hidden reports whether an item is left out of the lists, for being older
+// than max-age or in a hidden language.
+func (u *UI) hidden(item FeedItem) bool {
+	return u.tooOld(item) || item.Language != "" && u.hiddenLanguages[item.Language]
+}
+
+// showLanguages lists the languages of the items with how many there are,
+// for showing or hiding each with Enter. Items whose feed doesn't give a
+// language are always shown.
+func (u *UI) showLanguages() {
+	counts := make(map[string]int)
+	for _, item := range u.items {
+		if item.Language != "" {
+			counts[item.Language]++
+		}
+	}
+	var languages []string
+	for language := range counts {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).SetTitle(" Languages (Enter to show or hide, Esc to close) ")
+	list.SetBackgroundColor(tcell.ColorDefault)
+	label := func(language string) string {
+		mark := "[x]"
+		if u.hiddenLanguages[language] {
+			mark = "[ ]"
+		}
+		return tview.Escape(fmt.Sprintf("%s %s (%d)", mark, language, counts[language]))
+	}
+	if len(languages) == 0 {
+		list.AddItem("No feed gives a language; set one with language= in feeds.csv", "", 0, nil)
+	}
+	for _, language := range languages {
+		list.AddItem(label(language), "", 0, nil)
+	}
+
+	list.SetSelectedFunc(func(i int, _, _ string, _ rune) {
+		if i >= len(languages) {
+			return
+		}
+		language := languages[i]
+		u.hiddenLanguages[language] = !u.hiddenLanguages[language]
+		list.SetItemText(i, label(language), "")
+		u.render()
+		if u.twoPane {
+			u.renderFeeds()
+		}
+	})
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || event.Rune() == 'q' {
+			u.pages.RemovePage("languages")
+			return nil
+		}
+		return event
+	})
+	u.pages.AddPage("languages", centered(list, 50, 16), true, true)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/rivo/tview"
+)
+
+// lemmyPrefix starts the URL of a Lemmy community: lemmy:COMMUNITY@INSTANCE,
+// as in lemmy:technology@lemmy.world.
+const lemmyPrefix = "lemmy:"
+
+// lemmyPosts is how many of a community's newest posts are fetched.
+const lemmyPosts = 50
+
+// The gofeed.Item Custom keys the Lemmy source passes the score, comment
+// count and comments page of a post under. Custom only gets elements
+// without a namespace from RSS, so feeds can't collide with them.
+const (
+	customScore       = "newseum:score"
+	customComments    = "newseum:comments"
+	customCommentsURL = "newseum:comments-url"
+)
+
+// lemmy returns the community and instance of a Lemmy community feed.
+func (s FeedSource) lemmy() (community, instance string, ok bool) {
+	if !strings.HasPrefix(s.URL, lemmyPrefix) {
+		return "", "", false
+	}
+	community, instance, _ = strings.Cut(strings.TrimPrefix(s.URL, lemmyPrefix), "@")
+	return strings.TrimSpace(strings.TrimPrefix(community, "!")), strings.TrimSpace(instance), true
+}
+
+// lemmyPostList is the part of the answer of /api/v3/post/list newseum
+// uses.
+type lemmyPostList struct {
+	Posts []struct {
+		Post struct {
+			ID           int    `json:"id"`
+			Name         string `json:"name"`
+			URL          string `json:"url"`
+			Body         string `json:"body"`
+			Published    string `json:"published"`
+			ApID         string `json:"ap_id"`
+			ThumbnailURL string `json:"thumbnail_url"`
+		} `json:"post"`
+		Creator struct {
+			Name string `json:"name"`
+		} `json:"creator"`
+		Community struct {
+			Title string `json:"title"`
+		} `json:"community"`
+		Counts struct {
+			Score    int `json:"score"`
+			Comments int `json:"comments"`
+		} `json:"counts"`
+	} `json:"posts"`
+	Error string `json:"error"`
+}
+
+// fetchLemmy fetches the newest posts of a Lemmy community through the API
+// of lemmy-instance, so their comments pages are on the user's instance,
+// or of the community's own instance when there is none or it doesn't
+// know the community.
+func fetchLemmy(ctx context.Context, source FeedSource) (*gofeed.Feed, int64, error) {
+	community, instance, _ := source.lemmy()
+	home := config.LemmyInstance
+	if home == "" {
+		home = instance
+	}
+	list, size, err := fetchLemmyPosts(ctx, source, home, community+"@"+instance)
+	if err != nil && home != instance && ctx.Err() == nil {
+		slog.Info("fetching the community from its instance", "community", community, "instance", instance, "err", err)
+		fromInstance, more, instanceErr := fetchLemmyPosts(ctx, source, instance, community+"@"+instance)
+		size += more
+		if instanceErr == nil {
+			list, home, err = fromInstance, instance, nil
+		}
+	}
+	if err != nil {
+		return nil, size, err
+	}
+
+	feed := &gofeed.Feed{
+		Title:    community + "@" + instance,
+		Link:     lemmyURL(home) + "/c/" + community + "@" + instance,
+		FeedType: "lemmy",
+	}
+	for _, post := range list.Posts {
+		if post.Community.Title != "" {
+			feed.Title = post.Community.Title
+		}
+		comments := lemmyURL(home) + "/post/" + strconv.Itoa(post.Post.ID)
+		link := post.Post.URL
+		if link == "" {
+			link = comments
+		}
+		item := &gofeed.Item{
+			Title:       post.Post.Name,
+			Link:        link,
+			GUID:        post.Post.ApID,
+			Description: lemmyBody(post.Post.Body, post.Post.URL),
+			Authors:     []*gofeed.Person{{Name: post.Creator.Name}},
+			Custom: map[string]string{
+				customScore:       strconv.Itoa(post.Counts.Score),
+				customComments:    strconv.Itoa(post.Counts.Comments),
+				customCommentsURL: comments,
+			},
+		}
+		if published, ok := parseLemmyTime(post.Post.Published); ok {
+			item.PublishedParsed = &published
+		}
+		if post.Post.ThumbnailURL != "" {
+			item.Image = &gofeed.Image{URL: post.Post.ThumbnailURL}
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed, size, nil
+}
+
+// fetchLemmyPosts asks an instance for a community's newest posts.
+func fetchLemmyPosts(ctx context.Context, source FeedSource, instance, community string) (*lemmyPostList, int64, error) {
+	client, err := feedClient(source.TLS)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := neturl.Values{
+		"community_name": {community},
+		"sort":           {"New"},
+		"limit":          {strconv.Itoa(lemmyPosts)},
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", lemmyURL(instance)+"/api/v3/post/list?"+query.Encode(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if reset, limited := rateLimited(req.URL.Host); limited {
+		return nil, 0, fmt.Errorf("rate limited by %s until %s", req.URL.Host, reset.Local().Format("15:04"))
+	}
+	req.Header.Set("User-Agent", "newseum")
+	req.Header.Set("Accept", "application/json")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		slog.Warn("fetch failed", "url", req.URL, "err", err, "elapsed", time.Since(start))
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	recordRateLimit(req.URL.Host, resp)
+	slog.Info("fetched Lemmy community", "url", req.URL, "status", resp.StatusCode, "elapsed", time.Since(start))
+
+	counter := &countingReader{r: resp.Body}
+	var list lemmyPostList
+	err = json.NewDecoder(counter).Decode(&list)
+	switch {
+	case list.Error != "":
+		return nil, counter.n, fmt.Errorf("%s: %s", instance, strings.ReplaceAll(list.Error, "_", " "))
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, counter.n, fmt.Errorf("http error: %s", resp.Status)
+	case err != nil:
+		return nil, counter.n, fmt.Errorf("%s didn't answer like a Lemmy instance: %v", instance, err)
+	}
+	return &list, counter.n, nil
+}
+
+// lemmyURL returns the address of an instance given as a host name, or as
+// a URL for one that isn't served over https.
+func lemmyURL(instance string) string {
+	if strings.Contains(instance, "://") {
+		return instance
+	}
+	return "https://" + instance
+}
+
+// parseLemmyTime parses a post's time, which Lemmy before 0.19 gives
+// without a time zone, in UTC.
+func parseLemmyTime(value string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse("2006-01-02T15:04:05.999999999", value); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// lemmyBody turns a post's Markdown body into simple HTML: paragraphs and
+// line breaks, with the link of a link post first.
+func lemmyBody(body, link string) string {
+	var sb strings.Builder
+	if link != "" {
+		fmt.Fprintf(&sb, "<p><a href=\"%s\">%s</a></p>", html.EscapeString(link), html.EscapeString(link))
+	}
+	for _, paragraph := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			sb.WriteString("<p>" + strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>") + "</p>")
+		}
+	}
+	return sb.String()
+}
+
+// formatCounts shows the score and comment count of a post in the item
+// list.
+func formatCounts(item FeedItem) string {
+	if item.CommentsURL == "" {
+		return ""
+	}
+	if config.ScreenReader {
+		return fmt.Sprintf("%d points, %d comments", item.Score, item.Comments)
+	}
+	return fmt.Sprintf("↑%d 💬%d", item.Score, item.Comments)
+}
+
+// openComments opens the comments page of the selected item.
+func (u *UI) openComments(index int) {
+	item := u.items[index]
+	if item.CommentsURL == "" {
+		u.setStatus("The item has no comments page")
+		return
+	}
+	err := checkURL(item.CommentsURL)
+	if err == nil {
+		err = openBrowser(item.CommentsURL)
+	}
+	if errors.Is(err, errNoBrowser) {
+		if err := copyToClipboard(item.CommentsURL); err != nil {
+			slog.Error("error copying comments URL", "err", err)
+		}
+		u.setStatus("No browser in this session; copied " + tview.Escape(item.CommentsURL))
+		return
+	}
+	if err != nil {
+		slog.Error("error opening comments", "url", item.CommentsURL, "err", err)
+		u.setStatus("[red]Error opening " + tview.Escape(item.CommentsURL))
+	}
+}
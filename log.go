@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// setupLogging sends log/slog output to XDG_STATE_HOME/newseum/log. Only
+// warnings and errors are written unless a lower level is requested with
+// --verbose or --debug.
+func setupLogging(level slog.Level) (io.Closer, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating log directory: %v", err)
+	}
+
+	path := filepath.Join(dir, "log")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening log file: %v", err)
+	}
+
+	handler := slog.NewTextHandler(file, &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(handler))
+	return file, nil
+}
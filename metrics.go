@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// feedMetrics are the figures the daemon keeps about a feed for /metrics.
+type feedMetrics struct {
+	name        string
+	up          bool
+	elapsed     time.Duration
+	bytes       int64
+	items       int
+	errors      int
+	lastSuccess time.Time
+}
+
+// daemonMetrics are the figures /metrics reports, since the daemon started.
+var daemonMetrics = struct {
+	sync.Mutex
+	feeds       map[string]*feedMetrics
+	refreshes   int
+	lastRefresh time.Time
+	refreshTime time.Duration
+	pushes      int
+}{feeds: make(map[string]*feedMetrics)}
+
+// recordFetch counts a feed's fetch for /metrics.
+func recordFetch(p fetchProgress) {
+	daemonMetrics.Lock()
+	defer daemonMetrics.Unlock()
+	m := daemonMetrics.feeds[p.Source.URL]
+	if m == nil {
+		m = &feedMetrics{}
+		daemonMetrics.feeds[p.Source.URL] = m
+	}
+	m.name = p.Source.Name
+	m.up = p.Err == nil
+	m.elapsed = p.Elapsed
+	m.bytes = p.Bytes
+	if p.Err != nil {
+		m.errors++
+		return
+	}
+	m.items = p.Items
+	m.lastSuccess = time.Now()
+}
+
+// recordRefresh counts a finished refresh of every feed for /metrics.
+func recordRefresh(elapsed time.Duration) {
+	daemonMetrics.Lock()
+	defer daemonMetrics.Unlock()
+	daemonMetrics.refreshes++
+	daemonMetrics.lastRefresh = time.Now()
+	daemonMetrics.refreshTime = elapsed
+}
+
+// recordPush counts content pushed by a WebSub hub for /metrics.
+func recordPush() {
+	daemonMetrics.Lock()
+	defer daemonMetrics.Unlock()
+	daemonMetrics.pushes++
+}
+
+// startMetrics serves /metrics on metrics-listen until ctx is done.
+func startMetrics(ctx context.Context) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", serveMetrics)
+	server := &http.Server{Addr: config.MetricsListen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	goSafe(func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("error serving metrics", "addr", config.MetricsListen, "err", err)
+		}
+	})
+	goSafe(func() {
+		<-ctx.Done()
+		server.Close()
+	})
+}
+
+// serveMetrics writes the metrics in the Prometheus text format.
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	items, _, err := savedItems()
+	if err != nil {
+		slog.Error("error loading read state", "err", err)
+	}
+	unread := 0
+	for _, item := range items {
+		if !item.Read {
+			unread++
+		}
+	}
+
+	daemonMetrics.Lock()
+	defer daemonMetrics.Unlock()
+	urls := make([]string, 0, len(daemonMetrics.feeds))
+	for url := range daemonMetrics.feeds {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	perFeed := func(name, kind, help string, value func(*feedMetrics) float64) {
+		writeMetricHeader(w, name, kind, help)
+		for _, url := range urls {
+			m := daemonMetrics.feeds[url]
+			fmt.Fprintf(w, "%s{feed=\"%s\",name=\"%s\"} %g\n", name, metricLabel(url), metricLabel(m.name), value(m))
+		}
+	}
+	perFeed("newseum_feed_up", "gauge", "Whether the feed's last fetch succeeded.", func(m *feedMetrics) float64 {
+		if m.up {
+			return 1
+		}
+		return 0
+	})
+	perFeed("newseum_feed_fetch_duration_seconds", "gauge", "How long the feed's last fetch took.", func(m *feedMetrics) float64 {
+		return m.elapsed.Seconds()
+	})
+	perFeed("newseum_feed_fetch_bytes", "gauge", "How much the feed's last fetch transferred.", func(m *feedMetrics) float64 {
+		return float64(m.bytes)
+	})
+	perFeed("newseum_feed_fetch_errors_total", "counter", "Failed fetches of the feed.", func(m *feedMetrics) float64 {
+		return float64(m.errors)
+	})
+	perFeed("newseum_feed_items", "gauge", "Items in the feed at its last successful fetch.", func(m *feedMetrics) float64 {
+		return float64(m.items)
+	})
+	perFeed("newseum_feed_last_success_timestamp_seconds", "gauge", "When the feed was last fetched successfully.", func(m *feedMetrics) float64 {
+		return metricTime(m.lastSuccess)
+	})
+
+	single := func(name, kind, help string, value float64) {
+		writeMetricHeader(w, name, kind, help)
+		fmt.Fprintf(w, "%s %g\n", name, value)
+	}
+	single("newseum_refreshes_total", "counter", "Refreshes of every feed.", float64(daemonMetrics.refreshes))
+	single("newseum_last_refresh_timestamp_seconds", "gauge", "When the last refresh finished.", metricTime(daemonMetrics.lastRefresh))
+	single("newseum_refresh_duration_seconds", "gauge", "How long the last refresh took.", daemonMetrics.refreshTime.Seconds())
+	single("newseum_websub_pushes_total", "counter", "Content pushed by WebSub hubs.", float64(daemonMetrics.pushes))
+	single("newseum_items", "gauge", "Items saved by the last fetch.", float64(len(items)))
+	single("newseum_unread_items", "gauge", "Unread items saved by the last fetch.", float64(unread))
+}
+
+func writeMetricHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// metricLabel escapes a label value.
+func metricLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// metricTime is a time in Unix seconds, or 0 if it never happened.
+func metricTime(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixMilli()) / 1000
+}
@@ -1,119 +1,280 @@
 package main
 
 import (
+	"context"
 	"encoding/csv"
+	"errors"
+	"flag"
 	"fmt"
+	"hash/fnv"
 	"io"
+	"log/slog"
+	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"runtime"
+	"slices"
 	"sort"
+	"strconv"
 	"regexp"
 	"strings"
 	"time"
 	"sync"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
 	"github.com/mmcdole/gofeed"
-	"github.com/rivo/tview"
 )
 
+// FeedSource is one line of feeds.csv: a name, a URL and optional
+// key=value columns.
 type FeedSource struct {
-	Name string
-	URL  string
+	Name     string
+	URL      string
+	Category string
+	// Language overrides the language the feed declares.
+	Language string
+	Glyph    string
+	Color    string
+	// Prefetch overrides the global prefetch setting when set.
+	Prefetch *bool
+	// MaxItems overrides the global max-items setting when non-zero.
+	MaxItems int
+	// Weight is how many items the feed shows per turn in the interleaved
+	// order; 0 counts as 1.
+	Weight int
+	TLS      tlsOptions
+	// Open is what Enter does with the feed's items: browser, player,
+	// reader or cmd:COMMAND. Empty picks by the link.
+	Open string
+	// Since hides the items published before the feed was subscribed to,
+	// when its backlog was declined.
+	Since time.Time
+	// Disabled feeds aren't fetched and their items aren't shown. Days and
+	// Months limit fetching to some days of the week or months of the year,
+	// for feeds that are only worth reading in season.
+	Disabled bool
+	Days     string
+	Months   string
+	// Pinned feeds are listed first, and Order places a feed in the feed
+	// list and the feed groups; feeds without one follow by name.
+	Pinned bool
+	Order  int
 }
 
 type FeedItem struct {
-	Title     string
-	Date      time.Time
-	FeedTitle string
-	Link      string
-	AudioURL  string
+	Title       string
+	Date        time.Time
+	FeedTitle   string
+	FeedGlyph   string
+	FeedColor   string
+	Category    string
+	// Language is the primary language tag of the item's feed, such as
+	// "en", or empty if the feed doesn't give one.
+	Language    string
+	Link        string
+	AudioURL    string
+	Description itemBody
+	Enclosures  []Enclosure
+	ImageURL    string
+	// Duration is the play time of the item's audio or video, 0 if the
+	// feed doesn't give it.
+	Duration time.Duration
+	// Score and Comments are the votes and comment count of a post in a
+	// Lemmy community, and CommentsURL its comments page.
+	Score       int
+	Comments    int
+	CommentsURL string
+
+	// FeedURL is the feeds.csv URL the item was fetched from, and SiteURL
+	// the homepage the feed belongs to.
+	FeedURL string
+	SiteURL string
+	// ContentHash identifies the item by its title and text, which stay the
+	// same when a feed republishes it under a new GUID or link.
+	ContentHash string
+	// UpdatedAt is when a fetch found the item's title or text changed,
+	// and PreviousTitle and PreviousDescription what they said before.
+	UpdatedAt           time.Time
+	PreviousTitle       string
+	PreviousDescription itemBody
+	// Prefetch is set when the article should be downloaded for offline
+	// reading.
+	Prefetch bool `json:"-"`
+	// searchBody is the folded text of the description, prepared in the
+	// background after a fetch so filtering doesn't convert it on the fly.
+	searchBody string
+
+	// ID identifies the item on the backend it came from, if any.
+	ID      string
+	Read    bool
+	Starred bool
+}
+
+// Enclosure is a file attached to an item, such as a podcast episode.
+type Enclosure struct {
+	URL    string
+	Type   string
+	Length int64 // in bytes, 0 if unknown
 }
 
 func main() {
-    fmt.Print("\033[H\033[2J")
+	defer handlePanic()
+
+	verbose := flag.Bool("verbose", false, "log fetch timings and HTTP statuses")
+	debug := flag.Bool("debug", false, "log everything, including parse details")
+	showVersion := flag.Bool("version", false, "print the version and exit")
+	profileName := flag.String("profile", os.Getenv("NEWSEUM_PROFILE"), "use the named profile's config, feeds and state")
+	flag.StringVar(&configFile, "config", "", "read settings from this file instead of ~/.config/newseum/config")
+	flag.StringVar(&feedsFile, "feeds", "", "read subscriptions from this file instead of ~/.config/newseum/feeds.csv")
+	var start startOptions
+	flag.BoolVar(&start.noFetch, "no-fetch", false, "show the items saved by the last fetch without fetching")
+	flag.StringVar(&start.filter, "filter", "", "start on a tab of the items matching this search")
+	flag.StringVar(&start.feed, "feed", "", "start in the feed list layout with this feed selected")
+	flag.StringVar(&start.view, "view", "", "start in this view: timeline, feeds or grouped")
+	flag.Parse()
+	if *showVersion {
+		fmt.Println(versionLine())
+		return
+	}
+	if err := start.check(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := setProfile(*profileName); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
 
-	feedSources, err := getFeedSources()
+	logLevel := slog.LevelWarn
+	if *debug {
+		logLevel = slog.LevelDebug
+	} else if *verbose {
+		logLevel = slog.LevelInfo
+	}
+	logFile, err := setupLogging(logLevel)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
+	defer logFile.Close()
 
-	items, err := fetchFeeds(feedSources)
-	if err != nil {
-		fmt.Println("Error fetching feeds:", err)
+	if flag.Arg(0) == "import" {
+		if err := runImport(flag.Args()[1:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 		return
 	}
 
-	app := tview.NewApplication()
-	table := tview.NewTable().SetSelectable(true, false)
-	table.SetBackgroundColor(tcell.ColorDefault)
-    table.SetSelectedStyle(tcell.StyleDefault.Background(tcell.ColorWhite).Foreground(tcell.ColorBlack))
+	if flag.Arg(0) == "bundles" {
+		if err := runBundles(flag.Args()[1:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	if flag.Arg(0) == "state" {
+		if err := runState(flag.Args()[1:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	if flag.Arg(0) == "build-info" {
+		if err := runBuildInfo(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	if flag.Arg(0) == "check" {
+		if err := runCheck(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	now := time.Now().UTC() // Use UTC for consistency
-	for i, item := range items {
-		dateStr := " " + formatDate(item.Date, now)
-		titleStr := FormatString(" " + CleanString(item.Title), 75)
-		feedStr := FormatString(" " + CleanString(item.FeedTitle), 25)
+    fmt.Print("\033[H\033[2J")
 
-		title := tview.NewTableCell(titleStr).SetTextColor(tcell.GetColor("red"))
-		feed := tview.NewTableCell(feedStr).SetTextColor(tcell.GetColor("green"))
+	config, err = loadConfig()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	setupResolver()
+	applyTheme()
+	applyScreenReader()
+
+	if flag.Arg(0) == "fix" {
+		if err := runFix(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	if flag.Arg(0) == "duplicates" {
+		if err := runDuplicates(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := resolveSecrets(&config); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if flag.Arg(0) == "daemon" {
+		if err := runDaemon(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
 
-		table.SetCell(i, 0, feed)
-		table.SetCell(i, 1, title)
-		table.SetCellSimple(i, 2, dateStr)
+	backend, err := newBackend(config)
+	if err != nil {
+		fmt.Println(err)
+		return
 	}
 
-	table.Select(0, 0).SetDoneFunc(func(key tcell.Key) {
-		if key == tcell.KeyEscape {
-			app.Stop()
-		}
-	}).SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Rune() {
-		case 'q':
-			app.Stop()
-			return nil
-		case 'g':
-			table.Select(0, 0)
-			table.ScrollToBeginning()
-		case 'G':
-			table.Select(len(items)-1, 0)
-			table.ScrollToEnd()
+	var feedSources []FeedSource
+	var state *readState
+	if backend == nil {
+		if needsSetup() {
+			saved, err := runSetup()
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if !saved {
+				return
+			}
+			applyTheme()
 		}
-		return event
-	})
 
-	app.SetMouseCapture(func(event *tcell.EventMouse, action tview.MouseAction) (*tcell.EventMouse, tview.MouseAction) {
-		if action == tview.MouseScrollDown {
-			app.QueueEvent(tcell.NewEventKey(tcell.KeyRune, 'j', tcell.ModNone))
-			return nil, 0 // Consume the event
-		} else if action == tview.MouseScrollUp {
-			app.QueueEvent(tcell.NewEventKey(tcell.KeyRune, 'k', tcell.ModNone))
-			return nil, 0 // Consume the event
+		feedSources, err = getFeedSources()
+		if err != nil {
+			fmt.Println(err)
+			return
 		}
-		return event, action
-	})
+		state, err = loadReadState()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
 
-    table.SetSelectedFunc(func(row, column int) {
-        if row >= 0 && row < len(items) {
-            var url string
-            if items[row].AudioURL != "" {
-                url = items[row].AudioURL
-            } else {
-                url = items[row].Link
-            }
-            err := openURL(url)
-            if err != nil {
-                fmt.Println("Error opening browser:", err)
-            }
-        }
-    })
-
-	if err := app.SetRoot(table, true).EnableMouse(true).Run(); err != nil {
+	if start.filter != "" {
+		config.Searches = append(config.Searches, SavedSearch{Name: "Filter", Query: start.filter})
+	}
+	ui := newUI(feedSources, backend, state)
+	ui.start(start)
+	ui.showLoadProblems()
+	if err := ui.run(); err != nil {
 		panic(err)
 	}
+	finishWrites()
 }
 
 func formatDate(date time.Time, now time.Time) string {
@@ -137,209 +298,601 @@ func formatDate(date time.Time, now time.Time) string {
     }
 }
 
-func CleanString(input string) string {
-	whitespaceRegex := regexp.MustCompile(`\s+`)
-	trimmed := whitespaceRegex.ReplaceAllString(input, " ")
-    trimmed = strings.ReplaceAll(trimmed, "[", "(")
-    trimmed = strings.ReplaceAll(trimmed, "]", ")")
+var whitespaceRegex = regexp.MustCompile(`\s+`)
 
+func CleanString(input string) string {
+	trimmed := whitespaceRegex.ReplaceAllString(stripControl(input), " ")
 	return strings.TrimSpace(trimmed)
 }
 
+// FormatString truncates or pads s to exactly length terminal cells, so
+// wide characters such as icons and CJK text keep the columns aligned.
 func FormatString(s string, length int) string {
-	if len(s) > length {
-		return s[:length] // Truncate to specified length
-	} else if len(s) < length {
-		return s + strings.Repeat(" ", length-len(s)) // Add spaces to make it specified length
+	width := runewidth.StringWidth(s)
+	if width > length {
+		return runewidth.FillRight(runewidth.Truncate(s, length, ""), length) // Truncate to specified length
+	} else if width < length {
+		return s + strings.Repeat(" ", length-width) // Add spaces to make it specified length
 	}
 	return s
 }
 
+// feedsPath returns the location of feeds.csv.
+func feedsPath() (string, error) {
+	if feedsFile != "" {
+		return feedsFile, nil
+	}
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "feeds.csv"), nil
+}
+
 func getFeedSources() ([]FeedSource, error) {
-	configDir := os.Getenv("XDG_CONFIG_HOME")
-	if configDir == "" {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return nil, fmt.Errorf("unable to determine home directory: %v", err)
-		}
-		configDir = filepath.Join(homeDir, ".config")
+	list, err := loadFeedList()
+	if err != nil {
+		return nil, err
 	}
+	feedProblems.set(list.problems)
+	return list.sources, nil
+}
 
-	filePath := filepath.Join(configDir, "newseum", "feeds.csv")
-	file, err := os.Open(filePath)
+// maxIncludeDepth stops include rows that include each other.
+const maxIncludeDepth = 8
+
+// feedList holds the feeds of feeds.csv and of the files it includes.
+type feedList struct {
+	sources  []FeedSource
+	problems []string
+	// files are feeds.csv and the files included, in the order read.
+	files []string
+}
+
+func loadFeedList() (*feedList, error) {
+	filePath, err := feedsPath()
 	if err != nil {
+		return nil, err
+	}
+	list := &feedList{}
+	if err := list.read(filePath, 0); err != nil {
 		return nil, fmt.Errorf("error opening file %s: %v\nPlease create the file and fill it with a CSV list of feed names and URLs", filePath, err)
 	}
+	return list, nil
+}
+
+// read adds the feeds of a file. A bad row is skipped, and a bad option
+// dropped, so the rest of the feeds still load; problems lists what was
+// left out. An "include,PATH" row reads another file there, with PATH
+// relative to this file's directory.
+func (l *feedList) read(filePath string, depth int) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
 	defer file.Close()
+	l.files = append(l.files, filepath.Clean(filePath))
 
 	reader := csv.NewReader(file)
-	reader.FieldsPerRecord = 2 // Expect 2 fields per record: name and URL
+	reader.FieldsPerRecord = -1 // Name and URL, then any number of options
+	reader.Comment = '#'        // Blank lines are skipped too
 
-	var feedSources []FeedSource
+	first := true
 	for {
 		record, err := reader.Read()
 		if err == io.EOF {
 			break
 		}
-		if err != nil {
-			return nil, fmt.Errorf("error reading CSV: %v", err)
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			l.problems = append(l.problems, fmt.Sprintf("%s:%d: %v", filePath, parseErr.StartLine, parseErr.Err))
+			continue
+		} else if err != nil {
+			return fmt.Errorf("error reading %s: %v", filePath, err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue // A line of spaces
+		}
+		header := first && isFeedsHeader(record)
+		first = false
+		if header {
+			continue // An optional header row
+		}
+		if len(record) < 2 {
+			l.problems = append(l.problems, fmt.Sprintf("%s:%d: expected a name and a URL", filePath, line))
+			continue
 		}
-		feedSources = append(feedSources, FeedSource{
+		if strings.TrimSpace(record[0]) == "include" {
+			l.include(filePath, line, strings.TrimSpace(record[1]), depth)
+			continue
+		}
+
+		source := FeedSource{
 			Name: strings.TrimSpace(record[0]),
 			URL:  strings.TrimSpace(record[1]),
-		})
+		}
+		if err := checkFeedURL(source.URL); err != nil {
+			l.problems = append(l.problems, fmt.Sprintf("%s:%d: %v", filePath, line, err))
+			continue
+		}
+		for _, field := range record[2:] {
+			if err := source.setOption(strings.TrimSpace(field)); err != nil {
+				l.problems = append(l.problems, fmt.Sprintf("%s:%d: %v", filePath, line, err))
+			}
+		}
+		l.sources = append(l.sources, source)
 	}
+	return nil
+}
 
-	return feedSources, nil
+// include reads the file named on an include row of filePath.
+func (l *feedList) include(filePath string, line int, name string, depth int) {
+	path := expandPath(name)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(filePath), path)
+	}
+	problem := ""
+	switch {
+	case depth >= maxIncludeDepth:
+		problem = "too many nested includes"
+	case slices.Contains(l.files, path):
+		problem = fmt.Sprintf("%s is included already", path)
+	default:
+		if err := l.read(path, depth+1); err != nil {
+			problem = fmt.Sprintf("can't include: %v", err)
+		}
+	}
+	if problem != "" {
+		l.problems = append(l.problems, fmt.Sprintf("%s:%d: %s", filePath, line, problem))
+	}
 }
 
-func fetchFeeds(feedSources []FeedSource) ([]FeedItem, error) {
-    var items []FeedItem
-    var mutex sync.Mutex
-    fp := gofeed.NewParser()
-
-    // Create channels for work distribution and results
-    jobs := make(chan FeedSource)
-    results := make(chan error)
-    
-    // Number of concurrent workers (can be adjusted)
-    workers := 5
-    
-    // Start worker pool
-    var wg sync.WaitGroup
-    for w := 0; w < workers; w++ {
-        wg.Add(1)
-        go func() {
-            defer wg.Done()
-            for source := range jobs {
-                feed, err := fp.ParseURL(source.URL)
-                if err != nil {
-                    results <- fmt.Errorf("error parsing feed %s: %v", source.URL, err)
-                    continue
-                }
-
-                feedTitle := source.Name
-                if feedTitle == "" {
-                    feedTitle = feed.Title
-                }
-
-                var feedItems []FeedItem
-                for _, item := range feed.Items {
-                    pubDate := time.Now().UTC()
-                    if item.PublishedParsed != nil {
-                        pubDate = item.PublishedParsed.UTC()
-                    }
-
-                    audioURL := ""
-                    for _, enclosure := range item.Enclosures {
-                        if strings.HasPrefix(enclosure.Type, "audio/") {
-                            audioURL = enclosure.URL
-                            break
-                        }
-                    }
-
-                    feedItems = append(feedItems, FeedItem{
-                        Title:     item.Title,
-                        Date:      pubDate,
-                        FeedTitle: feedTitle,
-                        Link:      item.Link,
-                        AudioURL:  audioURL,
-                    })
-                }
-
-                mutex.Lock()
-                items = append(items, feedItems...)
-                mutex.Unlock()
-                
-                results <- nil
-            }
-        }()
-    }
+// isFeedsHeader reports whether a row of feeds.csv is a "name,url" header.
+func isFeedsHeader(record []string) bool {
+	return len(record) >= 2 && strings.EqualFold(strings.TrimSpace(record[0]), "name") &&
+		strings.EqualFold(strings.TrimSpace(record[1]), "url")
+}
 
-    // Create progress counter
-    progress := 0
-    totalFeeds := len(feedSources)
-    
-    // Start a goroutine to distribute work
-    go func() {
-        for _, source := range feedSources {
-            jobs <- source
-        }
-        close(jobs)
-    }()
-
-    // Start a goroutine to collect results and update progress
-    go func() {
-        for range feedSources {
-            err := <-results
-            progress++
-            if err != nil {
-                fmt.Printf("\n%v", err)
-            }
-            fmt.Printf("\rFetching %d/%d feeds...", progress, totalFeeds)
-        }
-        wg.Wait()
-        close(results)
-    }()
-
-    // Wait for all workers to complete
-    wg.Wait()
-    fmt.Println("\rFinished fetching all feeds.           ")
-
-    // Sort items by date
-    sort.Slice(items, func(i, j int) bool {
-        return items[i].Date.After(items[j].Date)
-    })
-
-    return items, nil
+// webFeed reports whether the source is a feed fetched from its URL, rather
+// than a query, plugin or Lemmy feed.
+func (s FeedSource) webFeed() bool {
+	_, query := s.query()
+	_, _, plugin := s.plugin()
+	_, _, lemmy := s.lemmy()
+	return !query && !plugin && !lemmy
 }
 
-func openURL(url string) error {
-    lowerURL := strings.ToLower(url)
-    
-    // Check for media URLs
-    isAudio := regexp.MustCompile(`\.(mp3|wav)(?:\?.*)?$`).MatchString(lowerURL)
-    isYoutube := strings.Contains(lowerURL, "youtube.com") || strings.Contains(lowerURL, "youtu.be")
-    
-    if (isAudio || isYoutube) && runtime.GOOS == "linux" {
-        var mimeType string
-        if isYoutube {
-            mimeType = "video/mp4" // More appropriate for YouTube content
-        } else if strings.Contains(lowerURL, ".mp3") {
-            mimeType = "audio/mpeg"
-        } else {
-            mimeType = "audio/wav"
-        }
-
-        // Get default application for media type
-        cmd := exec.Command("xdg-mime", "query", "default", mimeType)
-        output, err := cmd.Output()
-        if err != nil {
-            return fmt.Errorf("error querying default media application: %v", err)
-        }
-        
-        desktopFile := strings.TrimSpace(string(output))
-        if desktopFile == "" {
-            return fmt.Errorf("no default application found for %s", mimeType)
-        }
-
-        // Launch the media file with the default application
-        return exec.Command("gtk-launch", desktopFile, url).Start()
-    }
+// checkFeedURL checks the URL column of feeds.csv: a query feed, a plugin
+// feed, a Lemmy community or a full http(s) URL.
+func checkFeedURL(rawURL string) error {
+	if strings.HasPrefix(rawURL, queryPrefix) {
+		return nil
+	}
+	if name, _, ok := (FeedSource{URL: rawURL}).plugin(); ok {
+		if name == "" {
+			return fmt.Errorf("expected plugin:NAME:ARGUMENT")
+		}
+		return nil
+	}
+	if community, instance, ok := (FeedSource{URL: rawURL}).lemmy(); ok {
+		if community == "" || instance == "" {
+			return fmt.Errorf("expected lemmy:COMMUNITY@INSTANCE")
+		}
+		return nil
+	}
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http(s) URL", rawURL)
+	}
+	return nil
+}
 
-    // For non-media files or non-Linux systems, use the original browser opening logic
-    var cmd string
-    var args []string
-
-    switch runtime.GOOS {
-    case "windows":
-        cmd = "cmd"
-        args = []string{"/c", "start"}
-    case "darwin":
-        cmd = "open"
-    default: // "linux", "freebsd", "openbsd", "netbsd"
-        cmd = "xdg-open"
-    }
-    args = append(args, url)
-    return exec.Command(cmd, args...).Start()
+// setOption applies a key=value column from feeds.csv.
+func (s *FeedSource) setOption(field string) error {
+	key, value, _ := strings.Cut(field, "=")
+	switch strings.TrimSpace(key) {
+	case "":
+		// Allow trailing commas
+	case "category":
+		s.Category = strings.TrimSpace(value)
+	case "glyph":
+		s.Glyph = strings.TrimSpace(value)
+	case "language":
+		s.Language = normalizeLanguage(value)
+	case "prefetch":
+		prefetch, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("prefetch must be true or false")
+		}
+		s.Prefetch = &prefetch
+	case "max-items":
+		maxItems, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || maxItems <= 0 {
+			return fmt.Errorf("max-items must be a positive number")
+		}
+		s.MaxItems = maxItems
+	case "weight":
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || weight <= 0 {
+			return fmt.Errorf("weight must be a positive number")
+		}
+		s.Weight = weight
+	case "color":
+		s.Color = strings.TrimSpace(value)
+		if tcell.GetColor(s.Color) == tcell.ColorDefault {
+			return fmt.Errorf("unknown color %q", s.Color)
+		}
+	case "open":
+		s.Open = strings.TrimSpace(value)
+		switch {
+		case s.Open == "browser", s.Open == "player", s.Open == "reader":
+		case strings.HasPrefix(s.Open, "cmd:") && strings.TrimSpace(strings.TrimPrefix(s.Open, "cmd:")) != "":
+		default:
+			return fmt.Errorf("open must be browser, player, reader or cmd:COMMAND")
+		}
+	case "since":
+		since, err := time.Parse(time.RFC3339, strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("since must be a date like 2006-01-02T15:04:05Z")
+		}
+		s.Since = since
+	case "disabled":
+		disabled, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("disabled must be true or false")
+		}
+		s.Disabled = disabled
+	case "days":
+		s.Days = strings.TrimSpace(value)
+		if _, err := parseSchedule(s.Days, dayNames); err != nil {
+			return fmt.Errorf("days must be days of the week like sat sun or mon-fri: %v", err)
+		}
+	case "months":
+		s.Months = strings.TrimSpace(value)
+		if _, err := parseSchedule(s.Months, monthNames); err != nil {
+			return fmt.Errorf("months must be months like dec or sep-may: %v", err)
+		}
+	case "pinned":
+		pinned, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("pinned must be true or false")
+		}
+		s.Pinned = pinned
+	case "order":
+		order, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || order <= 0 {
+			return fmt.Errorf("order must be a positive number")
+		}
+		s.Order = order
+	case "ca":
+		s.TLS.CAFile = strings.TrimSpace(value)
+	case "cert":
+		s.TLS.CertFile = strings.TrimSpace(value)
+	case "key":
+		s.TLS.KeyFile = strings.TrimSpace(value)
+	case "insecure":
+		insecure, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("insecure must be true or false")
+		}
+		s.TLS.Insecure = insecure
+	default:
+		return fmt.Errorf("unknown feed option %q", key)
+	}
+	return nil
+}
+
+// record returns the feeds.csv columns for the source.
+func (s FeedSource) record() []string {
+	record := []string{s.Name, s.URL}
+	if strings.HasPrefix(s.Name, "#") {
+		// Quoted with the space, so the row isn't read as a comment
+		record[0] = " " + s.Name
+	}
+	if s.Category != "" {
+		record = append(record, "category="+s.Category)
+	}
+	if s.Language != "" {
+		record = append(record, "language="+s.Language)
+	}
+	if s.Glyph != "" {
+		record = append(record, "glyph="+s.Glyph)
+	}
+	if s.Color != "" {
+		record = append(record, "color="+s.Color)
+	}
+	if s.Prefetch != nil {
+		record = append(record, "prefetch="+strconv.FormatBool(*s.Prefetch))
+	}
+	if s.MaxItems != 0 {
+		record = append(record, "max-items="+strconv.Itoa(s.MaxItems))
+	}
+	if s.Weight != 0 {
+		record = append(record, "weight="+strconv.Itoa(s.Weight))
+	}
+	if s.Open != "" {
+		record = append(record, "open="+s.Open)
+	}
+	if !s.Since.IsZero() {
+		record = append(record, "since="+s.Since.UTC().Format(time.RFC3339))
+	}
+	if s.Disabled {
+		record = append(record, "disabled=true")
+	}
+	if s.Days != "" {
+		record = append(record, "days="+s.Days)
+	}
+	if s.Months != "" {
+		record = append(record, "months="+s.Months)
+	}
+	if s.Pinned {
+		record = append(record, "pinned=true")
+	}
+	if s.Order != 0 {
+		record = append(record, "order="+strconv.Itoa(s.Order))
+	}
+	if s.TLS.CAFile != "" {
+		record = append(record, "ca="+s.TLS.CAFile)
+	}
+	if s.TLS.CertFile != "" {
+		record = append(record, "cert="+s.TLS.CertFile)
+	}
+	if s.TLS.KeyFile != "" {
+		record = append(record, "key="+s.TLS.KeyFile)
+	}
+	if s.TLS.Insecure {
+		record = append(record, "insecure=true")
+	}
+	return record
+}
+
+// fetchProgress reports a feed that finished fetching.
+type fetchProgress struct {
+	Done   int
+	Total  int
+	Source FeedSource
+	Err    error
+	// Bytes is how much the feed's download transferred.
+	Bytes int64
+	// Elapsed is how long the fetch took, and Items how many items the
+	// feed had.
+	Elapsed time.Duration
+	Items   int
+	// Fetched are the feed's items, newest first: those fetched, or the
+	// saved ones when fetching failed. report may change them before
+	// they're added to the result.
+	Fetched []FeedItem
+}
+
+// fetchFeeds fetches the feeds in parallel, calling report as each one
+// finishes, and returns their items newest first. Feeds that fail keep the
+// items saved by the last fetch. Once ctx is cancelled the remaining feeds
+// fail at once.
+func fetchFeeds(ctx context.Context, sources []FeedSource, report func(fetchProgress)) ([]FeedItem, error) {
+	// Query feeds only select from the other feeds' items, and feeds out of
+	// their days= or months= show the items saved when they were last
+	// fetched.
+	cached := loadItemCache()
+	var items []FeedItem
+	var feedSources []FeedSource
+	now := time.Now()
+	for _, source := range sources {
+		if _, ok := source.query(); ok {
+			continue
+		}
+		if !source.active(now) {
+			if !source.Disabled {
+				saved := cached[source.URL]
+				sortByDate(saved)
+				items = mergeByDate(items, saved)
+			}
+			continue
+		}
+		feedSources = append(feedSources, source)
+	}
+
+	fp := gofeed.NewParser()
+
+	// Each worker sorts the items of its feed, which are then merged into
+	// the timeline as they arrive.
+	type result struct {
+		source FeedSource
+		bytes   int64
+		err     error
+		elapsed time.Duration
+		items   int
+		fetched []FeedItem
+	}
+	jobs := make(chan FeedSource)
+	results := make(chan result)
+
+	// Number of concurrent workers (can be adjusted)
+	workers := 5
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		goSafe(func() {
+			defer wg.Done()
+			for source := range jobs {
+				start := time.Now()
+				feed, bytes, err := fetchFeed(ctx, fp, source)
+				if err != nil {
+					saved := cached[source.URL]
+					if len(saved) > 0 {
+						sortByDate(saved)
+						err = fmt.Errorf("%v (showing saved items)", err)
+					}
+					results <- result{source, bytes, err, time.Since(start), 0, saved}
+					continue
+				}
+
+				if source.Name == "" {
+					source.Name = resolveFeedTitle(source.URL, feed.Title)
+				}
+				fetched := feedItems(source, feed)
+				markUpdated(fetched, cached[source.URL])
+				sortByDate(fetched)
+				results <- result{source, bytes, nil, time.Since(start), len(fetched), fetched}
+			}
+		})
+	}
+
+	goSafe(func() {
+		for _, source := range feedSources {
+			jobs <- source
+		}
+		close(jobs)
+	})
+
+	// The feed's items are merged after they're reported, so what report
+	// sets on them, such as the read state, is kept.
+	for done := 1; done <= len(feedSources); done++ {
+		r := <-results
+		report(fetchProgress{Done: done, Total: len(feedSources), Source: r.source, Err: r.err, Bytes: r.bytes,
+			Elapsed: r.elapsed, Items: r.items, Fetched: r.fetched})
+		items = mergeByDate(items, r.fetched)
+	}
+	wg.Wait()
+	if err := saveRateLimits(); err != nil {
+		slog.Warn("error saving rate limits", "err", err)
+	}
+
+	return items, nil
+}
+
+// printProgress reports fetch progress on the terminal, for commands that
+// run without the interface. Errors are printed on lines of their own.
+func printProgress(p fetchProgress) {
+	if p.Err != nil {
+		fmt.Printf("\r\033[Kerror fetching %s: %v\n", p.Source.URL, p.Err)
+	}
+	fmt.Printf("\rFetching %d/%d feeds...", p.Done, p.Total)
+	if p.Done == p.Total {
+		fmt.Println()
+	}
+}
+
+// feedItems converts a parsed feed into items carrying the source's options.
+func feedItems(source FeedSource, feed *gofeed.Feed) []FeedItem {
+	prefetch := config.Prefetch
+	if source.Prefetch != nil {
+		prefetch = *source.Prefetch
+	}
+	if config.DataSaver {
+		prefetch = false
+	}
+
+	feedTitle := source.Name
+	if feedTitle == "" {
+		feedTitle = feed.Title
+	}
+	language := source.Language
+	if language == "" {
+		language = normalizeLanguage(feed.Language)
+	}
+
+	// Relative links are resolved against the site when the feed names one,
+	// as feeds are often served from another host (e.g. a CDN or
+	// FeedBurner), and against the feed otherwise.
+	base, _ := url.Parse(source.URL)
+	if siteURL, err := url.Parse(feed.Link); err == nil && siteURL.IsAbs() {
+		base = siteURL
+	}
+
+	var items []FeedItem
+	seen := make(map[string]bool)
+	for _, item := range feed.Items {
+		pubDate := time.Now().UTC()
+		if item.PublishedParsed != nil {
+			pubDate = item.PublishedParsed.UTC()
+		} else {
+			slog.Debug("item has no publish date", "feed", source.URL, "title", item.Title)
+		}
+		if pubDate.Before(source.Since) {
+			continue
+		}
+
+		audioURL := ""
+		var enclosures []Enclosure
+		for _, enclosure := range item.Enclosures {
+			enclosureURL := resolveURL(base, enclosure.URL)
+			if audioURL == "" && strings.HasPrefix(enclosure.Type, "audio/") {
+				audioURL = enclosureURL
+			}
+			length, _ := strconv.ParseInt(enclosure.Length, 10, 64)
+			enclosures = append(enclosures, Enclosure{
+				URL:    enclosureURL,
+				Type:   enclosure.Type,
+				Length: length,
+			})
+		}
+
+		description := item.Description
+		if description == "" {
+			description = item.Content
+		}
+
+		hash := contentHash(item.Title, description)
+		if seen[hash] {
+			slog.Debug("skipping republished item", "feed", source.URL, "title", item.Title)
+			continue
+		}
+		seen[hash] = true
+
+		score, _ := strconv.Atoi(item.Custom[customScore])
+		comments, _ := strconv.Atoi(item.Custom[customComments])
+
+		imageURL := ""
+		if item.Image != nil {
+			imageURL = item.Image.URL
+		} else if item.ITunesExt != nil {
+			imageURL = item.ITunesExt.Image
+		}
+
+		items = append(items, FeedItem{
+			Title:       item.Title,
+			Date:        pubDate,
+			FeedTitle:   feedTitle,
+			FeedGlyph:   source.Glyph,
+			FeedColor:   source.Color,
+			Category:    source.Category,
+			Language:    language,
+			Link:        cleanLink(resolveURL(base, item.Link)),
+			AudioURL:    audioURL,
+			Description: newItemBody(resolveHTML(base, description)),
+			Enclosures:  enclosures,
+			ImageURL:    resolveURL(base, imageURL),
+			Duration:    itemDuration(item),
+			Score:       score,
+			Comments:    comments,
+			CommentsURL: item.Custom[customCommentsURL],
+			FeedURL:     source.URL,
+			SiteURL:     resolveURL(base, feed.Link),
+			ContentHash: hash,
+			Prefetch:    prefetch,
+		})
+	}
+
+	maxItems := config.MaxItems
+	if source.MaxItems != 0 {
+		maxItems = source.MaxItems
+	}
+	if maxItems > 0 && len(items) > maxItems {
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].Date.After(items[j].Date)
+		})
+		items = items[:maxItems]
+	}
+	return items
+}
+
+// contentHash hashes an item's title and the text of its description,
+// ignoring markup and whitespace that rebuilding a feed may change.
+func contentHash(title, description string) string {
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(strings.Fields(title), " ")))
+	h.Write([]byte{0})
+	h.Write([]byte(htmlToText(description)))
+	return strconv.FormatUint(h.Sum64(), 16)
 }
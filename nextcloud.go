@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const nextcloudBatchSize = 1000
+
+// NextcloudBackend talks to the Nextcloud News app API (v1.2). baseURL is
+// the Nextcloud server and the password should be an app password.
+type NextcloudBackend struct {
+	apiURL   string
+	user     string
+	password string
+
+	mutex sync.Mutex // guards starKeys
+	// starKeys maps item IDs to the feed ID and GUID hash that the v1.2
+	// star endpoints are addressed by.
+	starKeys map[string]string
+}
+
+func newNextcloudBackend(baseURL, user, password string) (*NextcloudBackend, error) {
+	if baseURL == "" || user == "" {
+		return nil, fmt.Errorf("the nextcloud backend needs backend-url and backend-user")
+	}
+	return &NextcloudBackend{
+		apiURL:   strings.TrimSuffix(baseURL, "/") + "/index.php/apps/news/api/v1-2",
+		user:     user,
+		password: password,
+		starKeys: make(map[string]string),
+	}, nil
+}
+
+func (b *NextcloudBackend) request(ctx context.Context, method, path string, body any, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, b.apiURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(b.user, b.password)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
+
+type nextcloudItem struct {
+	ID            int64  `json:"id"`
+	GUIDHash      string `json:"guidHash"`
+	URL           string `json:"url"`
+	Title         string `json:"title"`
+	PubDate       int64  `json:"pubDate"`
+	Body          string `json:"body"`
+	EnclosureMime string `json:"enclosureMime"`
+	EnclosureLink string `json:"enclosureLink"`
+	FeedID        int64  `json:"feedId"`
+	Unread        bool   `json:"unread"`
+	Starred       bool   `json:"starred"`
+}
+
+func (b *NextcloudBackend) Fetch(ctx context.Context) ([]FeedItem, error) {
+	var folders struct {
+		Folders []struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+		} `json:"folders"`
+	}
+	if err := b.request(ctx, "GET", "/folders", nil, &folders); err != nil {
+		return nil, fmt.Errorf("error fetching folders: %v", err)
+	}
+	folderNames := make(map[int64]string)
+	for _, folder := range folders.Folders {
+		folderNames[folder.ID] = folder.Name
+	}
+
+	var feeds struct {
+		Feeds []struct {
+			ID       int64  `json:"id"`
+			Title    string `json:"title"`
+			FolderID int64  `json:"folderId"`
+		} `json:"feeds"`
+	}
+	if err := b.request(ctx, "GET", "/feeds", nil, &feeds); err != nil {
+		return nil, fmt.Errorf("error fetching feeds: %v", err)
+	}
+	feedTitles := make(map[int64]string)
+	feedFolders := make(map[int64]string)
+	for _, feed := range feeds.Feeds {
+		feedTitles[feed.ID] = feed.Title
+		feedFolders[feed.ID] = folderNames[feed.FolderID]
+	}
+
+	query := url.Values{
+		"batchSize": {strconv.Itoa(nextcloudBatchSize)},
+		"type":      {"3"}, // all items
+		"id":        {"0"},
+		"getRead":   {"true"},
+	}
+	var result struct {
+		Items []nextcloudItem `json:"items"`
+	}
+	if err := b.request(ctx, "GET", "/items?"+query.Encode(), nil, &result); err != nil {
+		return nil, fmt.Errorf("error fetching items: %v", err)
+	}
+
+	b.mutex.Lock()
+	var items []FeedItem
+	for _, ni := range result.Items {
+		item := FeedItem{
+			ID:          strconv.FormatInt(ni.ID, 10),
+			Title:       ni.Title,
+			Date:        time.Unix(ni.PubDate, 0).UTC(),
+			FeedTitle:   feedTitles[ni.FeedID],
+			Category:    feedFolders[ni.FeedID],
+			Link:        cleanLink(ni.URL),
+			Description: newItemBody(ni.Body),
+			Read:        !ni.Unread,
+			Starred:     ni.Starred,
+		}
+		if ni.EnclosureLink != "" {
+			item.Enclosures = []Enclosure{{URL: ni.EnclosureLink, Type: ni.EnclosureMime}}
+			if strings.HasPrefix(ni.EnclosureMime, "audio/") {
+				item.AudioURL = ni.EnclosureLink
+			}
+		}
+		b.starKeys[item.ID] = fmt.Sprintf("%d/%s", ni.FeedID, ni.GUIDHash)
+		items = append(items, item)
+	}
+	b.mutex.Unlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Date.After(items[j].Date)
+	})
+	return items, nil
+}
+
+func (b *NextcloudBackend) MarkRead(item FeedItem, read bool) error {
+	action := "unread"
+	if read {
+		action = "read"
+	}
+	if err := b.request(context.Background(), "PUT", "/items/"+item.ID+"/"+action, nil, nil); err != nil {
+		return fmt.Errorf("error updating %s: %v", item.Title, err)
+	}
+	return nil
+}
+
+func (b *NextcloudBackend) SetStarred(item FeedItem, starred bool) error {
+	b.mutex.Lock()
+	key, ok := b.starKeys[item.ID]
+	b.mutex.Unlock()
+	if !ok {
+		return fmt.Errorf("error updating %s: unknown item", item.Title)
+	}
+
+	action := "unstar"
+	if starred {
+		action = "star"
+	}
+	if err := b.request(context.Background(), "PUT", "/items/"+key+"/"+action, nil, nil); err != nil {
+		return fmt.Errorf("error updating %s: %v", item.Title, err)
+	}
+	return nil
+}
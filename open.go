@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+var audioURLRegex = regexp.MustCompile(`\.(mp3|wav)(?:\?.*)?$`)
+
+// openURL opens a link with the platform's default handler. Audio files and
+// YouTube links are handed to a media player where the platform supports it.
+func openURL(url string) error {
+	if err := checkURL(url); err != nil {
+		return err
+	}
+	lowerURL := strings.ToLower(url)
+
+	// Check for media URLs
+	isAudio := audioURLRegex.MatchString(lowerURL)
+	isYoutube := strings.Contains(lowerURL, "youtube.com") || strings.Contains(lowerURL, "youtu.be")
+
+	if isAudio || isYoutube {
+		return playURL(url)
+	}
+
+	return openBrowser(url)
+}
+
+// openerTemplates are the opener settings that have a name. %b is the
+// terminal browser and %u the link.
+var openerTemplates = map[string]string{
+	"tmux-window": "tmux new-window %b %u",
+	"tmux-pane":   "tmux split-window -h %b %u",
+	"wezterm":     "wezterm cli spawn -- %b %u",
+}
+
+// Terminal browsers looked for when terminal-browser isn't set.
+var terminalBrowsers = []string{"w3m", "lynx", "elinks", "links"}
+
+// errNoBrowser is returned when a page can't be opened because the session
+// has no graphical browser and no terminal browser is installed.
+var errNoBrowser = errors.New("no browser available in this session")
+
+// headless reports whether newseum runs over SSH or without a graphical
+// session, where the desktop's browser can't be shown.
+func headless() bool {
+	if os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != "" {
+		return true
+	}
+	switch runtime.GOOS {
+	case "darwin", "windows":
+		return false
+	}
+	return os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == ""
+}
+
+// terminalBrowser returns the terminal-browser command, or the first of
+// terminalBrowsers that is installed.
+func terminalBrowser() []string {
+	if browser := strings.Fields(config.TerminalBrowser); len(browser) > 0 {
+		return browser
+	}
+	for _, name := range terminalBrowsers {
+		if _, err := exec.LookPath(name); err == nil {
+			return []string{name}
+		}
+	}
+	return nil
+}
+
+// openBrowser opens a web page in the desktop's browser, or with the opener
+// from the config, e.g. a terminal browser in a new tmux window. In a
+// headless session without an opener, a terminal browser is used instead.
+func openBrowser(url string) error {
+	if config.Opener != "" {
+		return runOpener(config.Opener, url)
+	}
+	if !headless() {
+		return openDefault(url)
+	}
+
+	browser := terminalBrowser()
+	switch {
+	case browser == nil:
+		return errNoBrowser
+	case os.Getenv("TMUX") != "":
+		return runOpener("tmux-window", url)
+	case activeApp != nil:
+		return runInTerminal(browser, url)
+	}
+	return errNoBrowser
+}
+
+// runInTerminal runs a command with url in place of the interface until it
+// exits.
+func runInTerminal(command []string, url string) error {
+	if err := checkURL(url); err != nil {
+		return err
+	}
+	var err error
+	activeApp.Suspend(func() {
+		cmd := exec.Command(command[0], append(command[1:], url)...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		err = cmd.Run()
+	})
+	return err
+}
+
+// runOpener opens url with an opener template. The template is split into
+// words before the link is put in, so the link is never seen by a shell.
+func runOpener(template, url string) error {
+	if err := checkURL(url); err != nil {
+		return err
+	}
+	if named, ok := openerTemplates[template]; ok {
+		template = named
+	}
+	browser := terminalBrowser()
+	if browser == nil {
+		browser = []string{"w3m"}
+	}
+	var args []string
+	for _, word := range strings.Fields(template) {
+		switch word {
+		case "%b":
+			args = append(args, browser...)
+		case "%u":
+			args = append(args, url)
+		default:
+			args = append(args, strings.ReplaceAll(word, "%u", url))
+		}
+	}
+	return launch(args[0], args[1:]...)
+}
+
+// playURL plays a link with the player from the config, or the desktop's
+// default application for its media type.
+func playURL(url string) error {
+	if err := checkURL(url); err != nil {
+		return err
+	}
+	if config.Player != "" {
+		player := strings.Fields(config.Player)
+		return launch(player[0], append(player[1:], url)...)
+	}
+	if headless() && activeApp != nil {
+		// Audio plays in the terminal; there's nowhere to show video.
+		if _, err := exec.LookPath("mpv"); err == nil {
+			return runInTerminal([]string{"mpv", "--no-video"}, url)
+		}
+	}
+
+	lowerURL := strings.ToLower(url)
+	mimeType := "video/mp4" // YouTube and other video
+	if strings.Contains(lowerURL, ".mp3") {
+		mimeType = "audio/mpeg"
+	} else if strings.Contains(lowerURL, ".wav") {
+		mimeType = "audio/wav"
+	}
+	return openMedia(url, mimeType)
+}
+
+// viewImage shows an image with the image viewer from the config, or else
+// in the browser.
+func viewImage(url string) error {
+	if err := checkURL(url); err != nil {
+		return err
+	}
+	if config.ImageViewer != "" {
+		viewer := strings.Fields(config.ImageViewer)
+		return launch(viewer[0], append(viewer[1:], url)...)
+	}
+	return openBrowser(url)
+}
+
+// openWith opens a link with a feed's open option: "browser", "player", or
+// "cmd:" followed by a command that gets the link appended. The reader is
+// handled by the interface.
+func openWith(action, url string) error {
+	if err := checkURL(url); err != nil {
+		return err
+	}
+	switch {
+	case action == "browser":
+		return openBrowser(url)
+	case action == "player":
+		return playURL(url)
+	case strings.HasPrefix(action, "cmd:"):
+		command := strings.Fields(strings.TrimPrefix(action, "cmd:"))
+		return launch(command[0], append(command[1:], url)...)
+	}
+	return openURL(url)
+}
+
+// Built-in archive services for the alternate open action.
+var archiveServices = map[string]string{
+	"wayback":       "https://web.archive.org/web/%s",
+	"archive.today": "https://archive.ph/newest/%s",
+	"12ft":          "https://12ft.io/%s",
+}
+
+// archiveURL rewrites a link through the archive service from the config:
+// one of the built-in names, or a prefix where %s (or the end of it) is
+// replaced by the link.
+func archiveURL(link string) string {
+	service := config.Archive
+	if service == "" {
+		service = "wayback"
+	}
+	if template, ok := archiveServices[service]; ok {
+		service = template
+	}
+	if !strings.Contains(service, "%s") {
+		service += "%s"
+	}
+	return strings.Replace(service, "%s", link, 1)
+}
+
+// launch starts a program under the process supervisor without waiting
+// for it.
+func launch(name string, args ...string) error {
+	_, err := startProcess(exec.Command(name, args...))
+	return err
+}
@@ -0,0 +1,11 @@
+package main
+
+func openDefault(url string) error {
+	return launch("open", url)
+}
+
+// openMedia leaves the choice of player to Launch Services, which already
+// picks the default application for the URL.
+func openMedia(url, mimeType string) error {
+	return openDefault(url)
+}
@@ -0,0 +1,31 @@
+//go:build !windows && !darwin
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// openDefault opens url with xdg-open ("linux", "freebsd", "openbsd", "netbsd").
+func openDefault(url string) error {
+	return launch("xdg-open", url)
+}
+
+// openMedia launches the desktop's default application for mimeType.
+func openMedia(url, mimeType string) error {
+	// Get default application for media type
+	output, err := exec.Command("xdg-mime", "query", "default", mimeType).Output()
+	if err != nil {
+		return fmt.Errorf("error querying default media application: %v", err)
+	}
+
+	desktopFile := strings.TrimSpace(string(output))
+	if desktopFile == "" {
+		return fmt.Errorf("no default application found for %s", mimeType)
+	}
+
+	// Launch the media file with the default application
+	return launch("gtk-launch", desktopFile, url)
+}
@@ -0,0 +1,23 @@
+package main
+
+import "os/exec"
+
+// Players tried, in order, for audio and video links.
+var mediaPlayers = []string{"mpv", "vlc"}
+
+// openDefault uses FileProtocolHandler rather than "cmd /c start", which
+// treats & in query strings as a command separator.
+func openDefault(url string) error {
+	return launch("rundll32", "url.dll,FileProtocolHandler", url)
+}
+
+// openMedia plays url with the first installed media player, falling back to
+// the browser when none is found.
+func openMedia(url, mimeType string) error {
+	for _, player := range mediaPlayers {
+		if path, err := exec.LookPath(player); err == nil {
+			return launch(path, url)
+		}
+	}
+	return openDefault(url)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mmcdole/gofeed"
+	"github.com/rivo/tview"
+)
+
+// pluginPrefix starts the URL of a feed whose items come from a plugin:
+// plugin:NAME:ARGUMENT, such as plugin:bluesky:alice.bsky.social.
+const pluginPrefix = "plugin:"
+
+// pluginProtocol is the version of the requests sent to plugins, raised
+// when they change incompatibly.
+const pluginProtocol = 1
+
+// pluginTimeout is how long a plugin may run before it is killed.
+const pluginTimeout = time.Minute
+
+// A plugin is an executable named newseum-NAME, in the plugins directory
+// next to feeds.csv or on the PATH. newseum runs it once per request,
+// writes the request to its standard input as JSON and reads the answer
+// from its standard output; a plugin that fails exits with a non-zero
+// status and says why on standard error.
+//
+// For a plugin feed the request is {"protocol": 1, "method": "fetch",
+// "argument": ARGUMENT} and the answer is a feed, normally a JSON Feed
+// (https://jsonfeed.org), though RSS and Atom are read too. For an action
+// it is {"protocol": 1, "method": "action", "action": ACTION, "item":
+// {...}} and the answer is nothing or {"message": TEXT} to show.
+type pluginRequest struct {
+	Protocol int         `json:"protocol"`
+	Method   string      `json:"method"`
+	Argument string      `json:"argument,omitempty"`
+	Action   string      `json:"action,omitempty"`
+	Item     *pluginItem `json:"item,omitempty"`
+}
+
+// pluginItem is the item an action is run on.
+type pluginItem struct {
+	Title       string      `json:"title"`
+	Link        string      `json:"link,omitempty"`
+	Date        time.Time   `json:"date"`
+	Description string      `json:"description,omitempty"`
+	FeedTitle   string      `json:"feed_title"`
+	FeedURL     string      `json:"feed_url"`
+	Enclosures  []Enclosure `json:"enclosures,omitempty"`
+	Read        bool        `json:"read"`
+	Starred     bool        `json:"starred"`
+}
+
+// PluginAction is a plugin-action from the config: an action of a plugin
+// offered on the selected item under Label.
+type PluginAction struct {
+	Label  string
+	Plugin string
+	Action string
+}
+
+// plugin returns the plugin name and argument of a plugin feed.
+func (s FeedSource) plugin() (name, arg string, ok bool) {
+	if !strings.HasPrefix(s.URL, pluginPrefix) {
+		return "", "", false
+	}
+	name, arg, _ = strings.Cut(strings.TrimPrefix(s.URL, pluginPrefix), ":")
+	return strings.TrimSpace(name), strings.TrimSpace(arg), true
+}
+
+// pluginPath finds the executable of the named plugin.
+func pluginPath(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid plugin name %q", name)
+	}
+	executable := "newseum-" + name
+	if dir, err := configDir(); err == nil {
+		path := filepath.Join(dir, "plugins", executable)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	path, err := exec.LookPath(executable)
+	if err != nil {
+		return "", fmt.Errorf("plugin %s not found: install %s in the plugins directory or on the PATH", name, executable)
+	}
+	return path, nil
+}
+
+// runPlugin sends a request to a plugin and returns its answer.
+func runPlugin(ctx context.Context, name string, request pluginRequest) ([]byte, error) {
+	path, err := pluginPath(name)
+	if err != nil {
+		return nil, err
+	}
+	request.Protocol = pluginProtocol
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pluginTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	start := time.Now()
+	err = cmd.Run()
+	slog.Info("ran plugin", "plugin", name, "method", request.Method, "elapsed", time.Since(start), "err", err)
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("plugin %s: %s", name, lastLine(message))
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("plugin %s took longer than %v", name, pluginTimeout)
+		}
+		return nil, fmt.Errorf("plugin %s: %v", name, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// lastLine returns the last line of a plugin's error output, which is
+// usually the error itself.
+func lastLine(text string) string {
+	if i := strings.LastIndex(text, "\n"); i >= 0 {
+		return strings.TrimSpace(text[i+1:])
+	}
+	return text
+}
+
+// fetchPlugin fetches the items of a plugin feed.
+func fetchPlugin(ctx context.Context, fp *gofeed.Parser, source FeedSource) (*gofeed.Feed, int64, error) {
+	name, arg, _ := source.plugin()
+	output, err := runPlugin(ctx, name, pluginRequest{Method: "fetch", Argument: arg})
+	if err != nil {
+		return nil, 0, err
+	}
+	feed, err := fp.Parse(bytes.NewReader(output))
+	if err != nil {
+		return nil, int64(len(output)), fmt.Errorf("plugin %s printed no feed: %v", name, err)
+	}
+	return feed, int64(len(output)), nil
+}
+
+// runPluginAction runs a plugin action on an item and returns the message
+// the plugin gave, if any.
+func runPluginAction(action PluginAction, item FeedItem) (string, error) {
+	output, err := runPlugin(context.Background(), action.Plugin, pluginRequest{
+		Method: "action",
+		Action: action.Action,
+		Item: &pluginItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Date:        item.Date,
+			Description: item.Description.String(),
+			FeedTitle:   item.FeedTitle,
+			FeedURL:     item.FeedURL,
+			Enclosures:  item.Enclosures,
+			Read:        item.Read,
+			Starred:     item.Starred,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	var answer struct {
+		Message string `json:"message"`
+	}
+	if len(bytes.TrimSpace(output)) > 0 {
+		if err := json.Unmarshal(output, &answer); err != nil {
+			return "", fmt.Errorf("plugin %s gave an invalid answer: %v", action.Plugin, err)
+		}
+	}
+	return answer.Message, nil
+}
+
+// showPluginActions lists the plugin actions from the config; Enter runs
+// the selected one on the item.
+func (u *UI) showPluginActions(index int) {
+	if len(config.PluginActions) == 0 {
+		u.setStatus("No plugin actions; add plugin-action lines to the config")
+		return
+	}
+	item := u.items[index]
+
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).SetTitle(" Actions (Enter to run, Esc to close) ")
+	list.SetBackgroundColor(tcell.ColorDefault)
+	for _, action := range config.PluginActions {
+		list.AddItem(tview.Escape(action.Label), "", 0, nil)
+	}
+	list.SetSelectedFunc(func(i int, _, _ string, _ rune) {
+		action := config.PluginActions[i]
+		u.pages.RemovePage("actions")
+		u.setStatus(tview.Escape(action.Label) + "...")
+		goSafe(func() {
+			message, err := runPluginAction(action, item)
+			u.app.QueueUpdateDraw(func() {
+				if err != nil {
+					slog.Error("error running plugin action", "action", action.Label, "err", err)
+					u.setStatus("[red]" + tview.Escape(action.Label) + ": " + tview.Escape(err.Error()))
+					return
+				}
+				if message == "" {
+					message = "done"
+				}
+				u.setStatus(tview.Escape(action.Label) + ": " + tview.Escape(CleanString(message)))
+			})
+		})
+	})
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || event.Rune() == 'q' {
+			u.pages.RemovePage("actions")
+			return nil
+		}
+		return event
+	})
+
+	u.pages.AddPage("actions", centered(list, 60, len(config.PluginActions)+2), true, true)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// prefetchWorkers bounds how many pages are downloaded at once; the crawler
+// additionally allows only one request at a time per host.
+const prefetchWorkers = 4
+
+// prefetchItems downloads the article page and image of every item whose
+// feed has prefetch enabled into the article cache, so the preview can show
+// the full text without a network connection, and indexes the articles for
+// search.
+func prefetchItems(items []FeedItem) {
+	type job struct {
+		url     string
+		article bool
+	}
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	for w := 0; w < prefetchWorkers; w++ {
+		wg.Add(1)
+		goSafe(func() {
+			defer wg.Done()
+			for job := range jobs {
+				body, err := articleCrawler.fetch(job.url)
+				if err != nil {
+					slog.Info("prefetch failed", "url", job.url, "err", err)
+					continue
+				}
+				if job.article {
+					if root, err := articleRoot(body); err == nil {
+						indexArticle(job.url, nodeText(root), false)
+					}
+				}
+			}
+		})
+	}
+
+	for _, item := range items {
+		if !item.Prefetch {
+			continue
+		}
+		if item.Link != "" {
+			jobs <- job{item.Link, true}
+		}
+		if item.ImageURL != "" {
+			jobs <- job{item.ImageURL, false}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := saveFullText(); err != nil {
+		slog.Warn("error saving full-text index", "err", err)
+	}
+	slog.Info("prefetch finished")
+}
+
+// articleTextLimit bounds how many extracted articles are kept in memory;
+// the whole lot is dropped when it is reached.
+const articleTextLimit = 500
+
+var (
+	articleTextMutex sync.Mutex
+	// articleTexts holds the text savedArticleText extracted for each link,
+	// "" for links with no saved article.
+	articleTexts = make(map[string]string)
+)
+
+// savedArticleText returns the text of an article from the cache,
+// however old, or "" if it was never downloaded. The text is kept, so only
+// the first call for a link reads and parses the page.
+func savedArticleText(link string) string {
+	if link == "" {
+		return ""
+	}
+	if text, ok := cachedArticleText(link); ok {
+		return text
+	}
+	var text string
+	if body, err := readArticleCache(link, 0); err == nil {
+		if root, err := articleRoot(body); err == nil {
+			text = nodeText(root)
+		}
+	}
+	articleTextMutex.Lock()
+	if len(articleTexts) >= articleTextLimit {
+		clear(articleTexts)
+	}
+	articleTexts[link] = text
+	articleTextMutex.Unlock()
+	return text
+}
+
+// cachedArticleText returns the text savedArticleText extracted for link,
+// if it has been asked for it since the article was last saved.
+func cachedArticleText(link string) (string, bool) {
+	articleTextMutex.Lock()
+	defer articleTextMutex.Unlock()
+	text, ok := articleTexts[link]
+	return text, ok
+}
+
+// forgetArticleText drops the text extracted for link when a new copy of
+// the article is saved.
+func forgetArticleText(link string) {
+	articleTextMutex.Lock()
+	delete(articleTexts, link)
+	articleTextMutex.Unlock()
+}
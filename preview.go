@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// previewText formats an item for the preview pane: a header, the
+// enclosures, and the description with its links listed at the end. Words
+// matching the filter are highlighted. The saved article replaces the
+// description once savedArticleText has extracted it.
+func previewText(item FeedItem, now time.Time, filter string) string {
+	query := parseQuery(filter)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[::b]%s[::-]\n", highlight(CleanString(item.Title), query, true))
+	theme := currentTheme()
+	fmt.Fprintf(&sb, "%s · %s", theme.feed(tview.Escape(CleanString(item.FeedTitle))), formatDate(item.Date, now))
+	if item.Duration > 0 {
+		sb.WriteString(" · " + formatDuration(item.Duration))
+	}
+	if item.CommentsURL != "" {
+		fmt.Fprintf(&sb, " · %d points · %d comments (C to open)", item.Score, item.Comments)
+	}
+	article, _ := cachedArticleText(item.Link)
+	if article != "" {
+		sb.WriteString(" · " + readingTime(article))
+	} else if description := item.Description.String(); description != "" {
+		sb.WriteString(" · " + readingTime(htmlToText(description)))
+	}
+	if !item.UpdatedAt.IsZero() {
+		sb.WriteString(" · updated " + formatDate(item.UpdatedAt, now) + " (c for changes)")
+	}
+	sb.WriteString("\n")
+	if item.Link != "" {
+		sb.WriteString(theme.dim(hyperlink(item.Link, tview.Escape(stripControl(item.Link)))) + "\n")
+	}
+
+	if len(item.Enclosures) > 0 {
+		sb.WriteString("\n[::b]Enclosures[::-] (e to choose)\n")
+		for i, enclosure := range item.Enclosures {
+			fmt.Fprintf(&sb, "%d. %s\n", i+1, tview.Escape(enclosureLabel(enclosure)))
+		}
+	}
+
+	if article != "" {
+		sb.WriteString("\n" + theme.dim("(saved article)") + "\n\n")
+		sb.WriteString(highlight(stripControl(article), query, false))
+	} else if text, links, images := descriptionMarkup(item.Description.String(), query); text != "" {
+		sb.WriteString("\n")
+		sb.WriteString(text)
+		if len(links) > 0 {
+			sb.WriteString("\n\n[::b]Links[::-] (f to choose)\n")
+			for i, link := range links {
+				fmt.Fprintf(&sb, "%s %s\n", theme.dim(tview.Escape(fmt.Sprintf("[%d]", i+1))), hyperlink(link.URL, tview.Escape(stripControl(link.URL))))
+			}
+		}
+		if len(images) > 0 {
+			sb.WriteString("\n\n[::b]Images[::-] (i to view)\n")
+			for i, image := range images {
+				fmt.Fprintf(&sb, "%s %s\n", theme.dim(fmt.Sprintf("(%d)", i+1)), hyperlink(image.URL, tview.Escape(stripControl(image.URL))))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// hyperlink makes text a terminal hyperlink (OSC 8) to url, unless
+// hyperlinks are turned off or the URL can't be put in a tview tag.
+func hyperlink(url, text string) string {
+	if config.NoHyperlinks || strings.ContainsAny(url, "[]") || checkURL(url) != nil {
+		return text
+	}
+	return "[:::" + url + "]" + text + "[:::-]"
+}
+
+// descriptionMarkup converts an HTML description to tview markup like
+// htmlToText does, with each link made a hyperlink and followed by a
+// footnote number for terminals that can't follow them, and each image,
+// which can't be shown, replaced by its alt text and a number. It returns
+// the links and the images in that order. Words matching the query are
+// highlighted.
+func descriptionMarkup(fragment string, query parsedQuery) (string, []Link, []Link) {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return tview.Escape(fragment), nil, nil
+	}
+
+	// Link text is collected in a builder of its own to wrap it in a tag.
+	var sb strings.Builder
+	out := &sb
+	var links, images []Link
+	numbers := make(map[string]int)
+	imageNumbers := make(map[string]int)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			out.WriteString(highlight(stripControl(n.Data), query, false))
+			return
+		case html.ElementNode:
+			if skippedElements[n.Data] {
+				return
+			}
+			if n.Data == "img" {
+				if image, ok := descriptionImage(n); ok {
+					number, seen := imageNumbers[image.URL]
+					if !seen {
+						images = append(images, image)
+						number = len(images)
+						imageNumbers[image.URL] = number
+					}
+					out.WriteString(currentTheme().dim(tview.Escape(fmt.Sprintf("[image: %s] (%d)", image.Text, number))))
+				}
+				return
+			}
+		}
+
+		href := ""
+		if n.Type == html.ElementNode && n.Data == "a" {
+			for _, attr := range n.Attr {
+				if attr.Key == "href" && (strings.HasPrefix(attr.Val, "http://") || strings.HasPrefix(attr.Val, "https://")) {
+					href = attr.Val
+				}
+			}
+		}
+		block := n.Type == html.ElementNode && blockElements[n.Data]
+		if block {
+			out.WriteString("\n\n")
+		}
+		parent := out
+		if href != "" {
+			out = &strings.Builder{}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if href != "" {
+			text := out.String()
+			out = parent
+			number, ok := numbers[href]
+			if !ok {
+				links = append(links, Link{Text: strings.TrimSpace(text), URL: href})
+				number = len(links)
+				numbers[href] = number
+			}
+			out.WriteString(hyperlink(href, text))
+			out.WriteString(tview.Escape(fmt.Sprintf("[%d]", number)))
+		}
+		if block {
+			out.WriteString("\n\n")
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	return tidyParagraphs(sb.String()), links, images
+}
+
+// descriptionImage returns an img element's alt text, or its file name when
+// it has none, and its address. Tracking pixels and images without an
+// http(s) source are left out.
+func descriptionImage(n *html.Node) (Link, bool) {
+	src := nodeAttr(n, "src")
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return Link{}, false
+	}
+	if width, height := nodeAttr(n, "width"), nodeAttr(n, "height"); width == "0" || width == "1" || height == "0" || height == "1" {
+		return Link{}, false
+	}
+	alt := strings.Join(strings.Fields(stripControl(nodeAttr(n, "alt"))), " ")
+	if alt == "" {
+		alt = downloadName(src)
+	}
+	return Link{Text: alt, URL: src}, true
+}
+
+// wordsPerMinute is the reading speed reading times are estimated with.
+const wordsPerMinute = 230
+
+// readingTime gives text's word count and the minutes it takes to read,
+// e.g. "1,240 words, 5 min read".
+func readingTime(text string) string {
+	words := len(strings.Fields(text))
+	minutes := (words + wordsPerMinute/2) / wordsPerMinute
+	if minutes < 1 {
+		minutes = 1
+	}
+	count := strconv.Itoa(words)
+	for i := len(count) - 3; i > 0; i -= 3 {
+		count = count[:i] + "," + count[i:]
+	}
+	unit := "words"
+	if words == 1 {
+		unit = "word"
+	}
+	return fmt.Sprintf("%s %s, %d min read", count, unit, minutes)
+}
+
+// enclosureLabel describes an enclosure by type, size and file name.
+func enclosureLabel(enclosure Enclosure) string {
+	kind := enclosure.Type
+	if kind == "" {
+		kind = "unknown type"
+	}
+	label := kind
+	if enclosure.Length > 0 {
+		label += ", " + humanSize(enclosure.Length)
+	}
+	return stripControl(label + "  " + downloadName(enclosure.URL))
+}
+
+// humanSize formats a byte count with a binary unit, e.g. "12.3 MiB".
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// maxProblemsShown is how many skipped lines the startup dialog lists.
+const maxProblemsShown = 10
+
+// fileProblems are the invalid lines skipped while reading a file, as
+// "path:line: problem". Reading the file again replaces them.
+type fileProblems struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+var (
+	configProblems fileProblems
+	feedProblems   fileProblems
+)
+
+func (p *fileProblems) set(lines []string) {
+	for _, line := range lines {
+		slog.Warn("skipped invalid line", "problem", line)
+	}
+	p.mu.Lock()
+	p.lines = lines
+	p.mu.Unlock()
+}
+
+func (p *fileProblems) get() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lines
+}
+
+// loadProblems lists the lines skipped in the config file and feeds.csv.
+func loadProblems() []string {
+	return append(append([]string(nil), configProblems.get()...), feedProblems.get()...)
+}
+
+// showLoadProblems tells which lines of the config file and feeds.csv were
+// skipped, if any, so a typo doesn't go unnoticed.
+func (u *UI) showLoadProblems() {
+	problems := loadProblems()
+	if len(problems) == 0 {
+		return
+	}
+	text := "Skipped these invalid lines:\n\n"
+	if len(problems) > maxProblemsShown {
+		text += strings.Join(problems[:maxProblemsShown], "\n") + fmt.Sprintf("\n... and %d more", len(problems)-maxProblemsShown)
+	} else {
+		text += strings.Join(problems, "\n")
+	}
+	u.showMessage(text + "\n\nnewseum check lists them all.")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// childProcess is a player, browser or TTS engine started by newseum.
+type childProcess struct {
+	cmd     *exec.Cmd
+	group   processGroup
+	grouped bool
+	label   string
+	started time.Time
+	done    chan struct{}
+}
+
+var (
+	processMutex sync.Mutex
+	processes    []*childProcess
+)
+
+// startProcess starts cmd and reaps it in the background once it exits, so
+// launched programs never linger as zombies. With detach enabled in the
+// config the child gets its own session and survives the terminal closing.
+// Either way it gets a process group, so stopping it also stops whatever
+// it launched.
+func startProcess(cmd *exec.Cmd) (*childProcess, error) {
+	if config.Detach {
+		detachProcess(cmd)
+	}
+	groupProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p := &childProcess{
+		cmd:     cmd,
+		label:   strings.Join(cmd.Args, " "),
+		started: time.Now(),
+		done:    make(chan struct{}),
+	}
+	if group, err := joinProcessGroup(cmd); err != nil {
+		slog.Warn("error grouping child process", "cmd", p.label, "err", err)
+	} else {
+		p.group, p.grouped = group, true
+	}
+
+	processMutex.Lock()
+	processes = append(processes, p)
+	processMutex.Unlock()
+
+	goSafe(func() {
+		cmd.Wait()
+		if p.grouped {
+			p.group.wait()
+		}
+
+		processMutex.Lock()
+		for i, other := range processes {
+			if other == p {
+				processes = append(processes[:i], processes[i+1:]...)
+				break
+			}
+		}
+		processMutex.Unlock()
+
+		if p.grouped {
+			p.group.close()
+		}
+		close(p.done)
+	})
+
+	return p, nil
+}
+
+// runningProcesses returns the children that haven't exited yet, oldest first.
+func runningProcesses() []*childProcess {
+	processMutex.Lock()
+	defer processMutex.Unlock()
+	return append([]*childProcess(nil), processes...)
+}
+
+// kill stops the child and the processes in its group.
+func (p *childProcess) kill() error {
+	if p.grouped {
+		err := p.group.kill()
+		if err == nil {
+			return nil
+		}
+		slog.Warn("error stopping process group", "cmd", p.label, "err", err)
+	}
+	if err := p.cmd.Process.Kill(); err != nil {
+		return fmt.Errorf("error stopping %s: %v", p.label, err)
+	}
+	return nil
+}
+
+func (p *childProcess) String() string {
+	return fmt.Sprintf("%s (%s)", p.label, time.Since(p.started).Round(time.Second))
+}
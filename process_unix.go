@@ -0,0 +1,57 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// detachProcess puts the child in a new session so the terminal's hangup
+// signal doesn't reach it.
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// groupProcess starts the child in a process group of its own, so killing
+// it also stops what it started: the player xdg-open hands a file to, or
+// the rest of a shell pipeline. A detached child already leads its own
+// session and with it a group.
+func groupProcess(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	if !cmd.SysProcAttr.Setsid {
+		cmd.SysProcAttr.Setpgid = true
+	}
+}
+
+// processGroup is the process group a child leads.
+type processGroup struct {
+	pid int
+}
+
+func joinProcessGroup(cmd *exec.Cmd) (processGroup, error) {
+	return processGroup{pid: cmd.Process.Pid}, nil
+}
+
+// kill kills every process in the group.
+func (g processGroup) kill() error {
+	err := syscall.Kill(-g.pid, syscall.SIGKILL)
+	if errors.Is(err, syscall.ESRCH) {
+		return nil
+	}
+	return err
+}
+
+// wait returns once every process in the group has exited, which may be
+// long after the child itself when it was only a launcher.
+func (g processGroup) wait() {
+	for syscall.Kill(-g.pid, 0) == nil {
+		time.Sleep(time.Second)
+	}
+}
+
+func (g processGroup) close() {}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const detachedProcess = 0x00000008
+
+// detachProcess starts the child without the console, so closing the
+// terminal window doesn't take it down.
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess,
+	}
+}
+
+// groupProcess does nothing on Windows, where the child is put in a job
+// object once it has started.
+func groupProcess(cmd *exec.Cmd) {}
+
+// processGroup is the job object holding a child and the processes it
+// starts, so killing it also stops the player a launcher hands a file to.
+type processGroup struct {
+	job windows.Handle
+}
+
+func joinProcessGroup(cmd *exec.Cmd) (processGroup, error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return processGroup{}, err
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(cmd.Process.Pid))
+	if err != nil {
+		windows.CloseHandle(job)
+		return processGroup{}, err
+	}
+	defer windows.CloseHandle(process)
+	if err := windows.AssignProcessToJobObject(job, process); err != nil {
+		windows.CloseHandle(job)
+		return processGroup{}, err
+	}
+	return processGroup{job: job}, nil
+}
+
+// kill terminates every process in the job.
+func (g processGroup) kill() error {
+	return windows.TerminateJobObject(g.job, 1)
+}
+
+// jobAccounting is JOBOBJECT_BASIC_ACCOUNTING_INFORMATION.
+type jobAccounting struct {
+	TotalUserTime             int64
+	TotalKernelTime           int64
+	ThisPeriodTotalUserTime   int64
+	ThisPeriodTotalKernelTime int64
+	TotalPageFaultCount       uint32
+	TotalProcesses            uint32
+	ActiveProcesses           uint32
+	TotalTerminatedProcesses  uint32
+}
+
+// wait returns once every process in the job has exited, which may be
+// long after the child itself when it was only a launcher.
+func (g processGroup) wait() {
+	for {
+		var info jobAccounting
+		err := windows.QueryInformationJobObject(g.job, windows.JobObjectBasicAccountingInformation,
+			uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info)), nil)
+		if err != nil || info.ActiveProcesses == 0 {
+			return
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func (g processGroup) close() {
+	windows.CloseHandle(g.job)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimit is the quota a host's rate-limit headers reported last.
+type rateLimit struct {
+	Limit     int       `json:"limit,omitempty"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+	Seen      time.Time `json:"seen"`
+}
+
+// rateLimits tracks the quotas of hosts with APIs that announce one, such as
+// Reddit, Mastodon and GitHub, so feeds from a host that is out of requests
+// aren't fetched until its quota resets; their saved items are shown
+// meanwhile. It is kept in the cache directory so quick restarts don't
+// forget it.
+var rateLimits = struct {
+	sync.Mutex
+	loaded bool
+	hosts  map[string]rateLimit
+}{}
+
+func rateLimitsPath() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ratelimits.json"), nil
+}
+
+// loadRateLimits reads the saved quotas if they haven't been yet. The caller
+// holds the lock.
+func loadRateLimits() {
+	if rateLimits.loaded {
+		return
+	}
+	rateLimits.loaded = true
+	rateLimits.hosts = make(map[string]rateLimit)
+	path, err := rateLimitsPath()
+	if err != nil {
+		return
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &rateLimits.hosts)
+	}
+}
+
+// saveRateLimits writes the quotas that haven't reset yet.
+func saveRateLimits() error {
+	rateLimits.Lock()
+	defer rateLimits.Unlock()
+	if !rateLimits.loaded {
+		return nil
+	}
+	now := time.Now()
+	for host, limit := range rateLimits.hosts {
+		if limit.Reset.Before(now) {
+			delete(rateLimits.hosts, host)
+		}
+	}
+	path, err := rateLimitsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(rateLimits.hosts)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// rateLimited reports whether a host has used up its quota, and when it
+// resets.
+func rateLimited(host string) (time.Time, bool) {
+	rateLimits.Lock()
+	defer rateLimits.Unlock()
+	loadRateLimits()
+	limit, ok := rateLimits.hosts[host]
+	if !ok || limit.Remaining > 0 || !time.Now().Before(limit.Reset) {
+		return time.Time{}, false
+	}
+	return limit.Reset, true
+}
+
+// recordRateLimit notes the quota a response reports: the X-RateLimit-*
+// headers of Reddit, GitHub and Mastodon or the standard RateLimit-* ones,
+// or a Retry-After on a 429 or 503.
+func recordRateLimit(host string, resp *http.Response) {
+	now := time.Now()
+	limit := rateLimit{Seen: now}
+	found := false
+	if remaining, ok := headerNumber(resp.Header, "X-RateLimit-Remaining", "RateLimit-Remaining"); ok {
+		limit.Remaining = int(remaining)
+		found = true
+		if total, ok := headerNumber(resp.Header, "X-RateLimit-Limit", "RateLimit-Limit"); ok {
+			limit.Limit = int(total)
+		} else if used, ok := headerNumber(resp.Header, "X-RateLimit-Used"); ok {
+			limit.Limit = int(used + remaining)
+		}
+		limit.Reset = resetTime(now, resp.Header.Get("X-RateLimit-Reset"), resp.Header.Get("RateLimit-Reset"))
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if after := resp.Header.Get("Retry-After"); after != "" {
+			limit.Remaining = 0
+			limit.Reset = retryAfter(now, after)
+			found = true
+		}
+	}
+	if !found || limit.Reset.IsZero() {
+		return
+	}
+
+	rateLimits.Lock()
+	defer rateLimits.Unlock()
+	loadRateLimits()
+	rateLimits.hosts[host] = limit
+	if limit.Remaining == 0 {
+		slog.Warn("rate limited", "host", host, "reset", limit.Reset)
+	}
+}
+
+// headerNumber returns the number at the start of the first of the headers
+// present. Reddit sends fractions ("598.0"), and RateLimit-Limit can carry a
+// policy after the number ("100, 100;w=60").
+func headerNumber(header http.Header, names ...string) (float64, bool) {
+	for _, name := range names {
+		value := strings.TrimSpace(header.Get(name))
+		if value == "" {
+			continue
+		}
+		if i := strings.IndexAny(value, ",;"); i >= 0 {
+			value = value[:i]
+		}
+		if n, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && n >= 0 {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// resetTime interprets the first non-empty reset header, which is seconds
+// from now (Reddit, RateLimit-Reset), a Unix time (GitHub) or an ISO 8601
+// time (Mastodon).
+func resetTime(now time.Time, values ...string) time.Time {
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			if n > 1e9 {
+				return time.Unix(int64(n), 0)
+			}
+			return now.Add(time.Duration(n * float64(time.Second)))
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// retryAfter interprets a Retry-After header, in seconds or an HTTP date.
+func retryAfter(now time.Time, value string) time.Time {
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		return now.Add(time.Duration(seconds) * time.Second)
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// hostRateLimit is a host's last reported quota, for the stats view.
+type hostRateLimit struct {
+	host string
+	rateLimit
+}
+
+// currentRateLimits lists the quotas that haven't reset yet, by host.
+func currentRateLimits() []hostRateLimit {
+	rateLimits.Lock()
+	defer rateLimits.Unlock()
+	loadRateLimits()
+	now := time.Now()
+	var limits []hostRateLimit
+	for host, limit := range rateLimits.hosts {
+		if limit.Reset.After(now) {
+			limits = append(limits, hostRateLimit{host, limit})
+		}
+	}
+	sort.Slice(limits, func(i, j int) bool {
+		return limits[i].host < limits[j].host
+	})
+	return limits
+}
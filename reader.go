@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// sourceOf returns the feeds.csv subscription an item was fetched from.
+func (u *UI) sourceOf(item FeedItem) (FeedSource, bool) {
+	for _, source := range u.sources {
+		if source.URL == item.FeedURL {
+			return source, true
+		}
+	}
+	return FeedSource{}, false
+}
+
+// showReader shows an item's article full screen. The saved copy is used
+// when there is one; otherwise the page is downloaded, and the description
+// is shown until it arrives or if it can't be fetched.
+func (u *UI) showReader(item FeedItem) {
+	view := u.fullScreenView("reader", item.Title)
+	theme := currentTheme()
+	header := fmt.Sprintf("[::b]%s[::-]\n%s · %s\n\n", tview.Escape(CleanString(item.Title)),
+		theme.feed(tview.Escape(CleanString(item.FeedTitle))), formatDate(item.Date, u.now))
+	show := func(text string) {
+		view.SetText(header + text)
+		view.ScrollToBeginning()
+	}
+
+	if text := savedArticleText(item.Link); text != "" {
+		show(tview.Escape(stripControl(text)))
+	} else {
+		description, _, _ := descriptionMarkup(item.Description.String(), parsedQuery{})
+		show(description)
+		if item.Link != "" && checkURL(item.Link) == nil {
+			goSafe(func() {
+				text, err := extractArticle(item.Link)
+				if err != nil {
+					slog.Error("error fetching article for the reader", "url", item.Link, "err", err)
+					return
+				}
+				if strings.TrimSpace(text) == "" {
+					return
+				}
+				u.app.QueueUpdateDraw(func() {
+					show(tview.Escape(stripControl(text)))
+				})
+			})
+		}
+	}
+}
+
+// showQuickLook shows the selected item's preview full screen, for reading
+// a long description without the narrow preview pane.
+func (u *UI) showQuickLook() {
+	index := u.selected()
+	if index < 0 {
+		return
+	}
+	item := u.items[index]
+	savedArticleText(item.Link)
+	view := u.fullScreenView("quicklook", item.Title)
+	view.SetText(previewText(item, u.now, u.filter))
+}
+
+// fullScreenView adds a page that fills the screen with a scrolling text
+// view titled title, closed with Esc or q.
+func (u *UI) fullScreenView(name, title string) *textPage {
+	view := newTextPage(2)
+	view.SetBorder(true).SetTitle(" " + tview.Escape(CleanString(title)) + " (Esc to close) ")
+	view.SetBackgroundColor(tcell.ColorDefault)
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || event.Rune() == 'q' {
+			u.pages.RemovePage(name)
+			return nil
+		}
+		return event
+	})
+	u.pages.AddPage(name, view, true, true)
+	return view
+}
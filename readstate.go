@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stateRetention is how long an unstarred item's state is kept after it
+// last changed. Starred items are kept for good.
+const stateRetention = 365 * 24 * time.Hour
+
+// itemState is the read and starred state of one item. Each flag carries the
+// time it last changed, and copies of the state file are merged by keeping
+// the newer value of each flag, so edits made on different machines never
+// conflict.
+type itemState struct {
+	Read      bool      `json:"read"`
+	ReadAt    time.Time `json:"readAt"`
+	Starred   bool      `json:"starred"`
+	StarredAt time.Time `json:"starredAt"`
+	// Hash is the item's content hash, to recognize it when the feed
+	// republishes it under another link.
+	Hash string `json:"hash,omitempty"`
+	// Feed is the URL of the item's feed; an item is only recognized by
+	// its hash in the same feed, so mirrors and cross-posts with the same
+	// content keep their own flags.
+	Feed string `json:"feed,omitempty"`
+}
+
+// readState keeps read and starred flags for feeds.csv items, which have no
+// server to remember them. The file can live in a synced folder (Syncthing,
+// Dropbox) to share the state between machines.
+type readState struct {
+	mu    sync.Mutex
+	path  string
+	items map[string]itemState
+}
+
+// stateFilePath returns sync-file from the config, or state.json in the
+// data directory: unlike the rest of the state directory, the flags can't be
+// recreated, so they are kept with the user's data.
+func stateFilePath() (string, error) {
+	if config.SyncFile != "" {
+		return config.SyncFile, nil
+	}
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "state.json")
+	if old, err := stateDir(); err == nil {
+		path = moveOldFile(filepath.Join(old, "state.json"), path)
+	}
+	return path, nil
+}
+
+// loadReadState reads the state file along with any conflicting copies the
+// sync tool left beside it, which are merged in and removed.
+func loadReadState() (*readState, error) {
+	path, err := stateFilePath()
+	if err != nil {
+		return nil, err
+	}
+	s := &readState{path: path, items: make(map[string]itemState)}
+	if err := s.mergeFile(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading state file %s: %v", path, err)
+	}
+
+	conflicts, err := conflictCopies(path)
+	if err != nil || len(conflicts) == 0 {
+		return s, nil
+	}
+	for _, conflict := range conflicts {
+		if err := s.mergeFile(conflict); err != nil {
+			slog.Warn("error merging conflicting state file", "path", conflict, "err", err)
+		}
+	}
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	for _, conflict := range conflicts {
+		os.Remove(conflict)
+	}
+	return s, nil
+}
+
+// conflictCopies finds the copies Syncthing ("state.sync-conflict-….json")
+// and Dropbox ("state (… conflicted copy ….json") make of a file changed on
+// two machines at once.
+func conflictCopies(path string) ([]string, error) {
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(path, ext)
+	matches, err := filepath.Glob(stem + "*" + ext)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []string
+	for _, match := range matches {
+		if match != path && strings.Contains(strings.ToLower(match), "conflict") {
+			conflicts = append(conflicts, match)
+		}
+	}
+	return conflicts, nil
+}
+
+// mergeFile merges the state saved in a file into s.
+func (s *readState) mergeFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var items map[string]itemState
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	for key, theirs := range items {
+		s.items[key] = mergeItemState(s.items[key], theirs)
+	}
+	return nil
+}
+
+func mergeItemState(a, b itemState) itemState {
+	if b.ReadAt.After(a.ReadAt) {
+		a.Read, a.ReadAt = b.Read, b.ReadAt
+	}
+	if b.StarredAt.After(a.StarredAt) {
+		a.Starred, a.StarredAt = b.Starred, b.StarredAt
+	}
+	if a.Hash == "" {
+		a.Hash = b.Hash
+	}
+	if a.Feed == "" {
+		a.Feed = b.Feed
+	}
+	return a
+}
+
+// save merges in changes written by other machines or instances since the
+// file was loaded, drops expired entries and writes the result. A lock file
+// beside the state file keeps instances on this machine from interleaving
+// their reads and writes and losing each other's changes.
+func (s *readState) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return fmt.Errorf("error locking state file: %v", err)
+	}
+	defer unlock()
+
+	if err := s.mergeFile(s.path); err != nil && !os.IsNotExist(err) {
+		slog.Warn("error rereading state file", "path", s.path, "err", err)
+	}
+
+	cutoff := time.Now().Add(-stateRetention)
+	for key, state := range s.items {
+		if !state.Starred && state.ReadAt.Before(cutoff) && state.StarredAt.Before(cutoff) {
+			delete(s.items, key)
+		}
+	}
+
+	data, err := json.MarshalIndent(s.items, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// itemKey identifies an item across fetches and machines.
+func itemKey(item FeedItem) string {
+	if item.ID != "" {
+		return item.ID
+	}
+	if item.Link != "" {
+		return item.Link
+	}
+	return item.FeedURL + "#" + item.Title
+}
+
+// hashKey identifies an item by its content hash within its feed.
+func hashKey(feedURL, hash string) string {
+	return feedURL + "#" + hash
+}
+
+// apply sets the saved flags on items, matching items whose link changed
+// by their content hash.
+func (s *readState) apply(items []FeedItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byHash := make(map[string]itemState)
+	for _, state := range s.items {
+		if state.Hash != "" && state.Feed != "" {
+			byHash[hashKey(state.Feed, state.Hash)] = state
+		}
+	}
+	for i := range items {
+		state, ok := s.items[itemKey(items[i])]
+		if !ok && items[i].ContentHash != "" {
+			state, ok = byHash[hashKey(items[i].FeedURL, items[i].ContentHash)]
+		}
+		if ok {
+			items[i].Read = state.Read
+			items[i].Starred = state.Starred
+		}
+	}
+}
+
+// updateAll records the current flags of several items and saves the file
+// once.
+func (s *readState) updateAll(items []FeedItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, item := range items {
+		key := itemKey(item)
+		state := s.items[key]
+		if state.Read != item.Read {
+			state.Read, state.ReadAt = item.Read, now
+		}
+		if state.Starred != item.Starred {
+			state.Starred, state.StarredAt = item.Starred, now
+		}
+		state.Hash, state.Feed = item.ContentHash, item.FeedURL
+		s.items[key] = state
+	}
+	return s.save()
+}
+
+// update records an item's current flags and saves the file.
+func (s *readState) update(item FeedItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	key := itemKey(item)
+	state, ok := s.items[key]
+	if !ok && item.ContentHash != "" {
+		// Carry over the state of the item this one republishes
+		for _, other := range s.items {
+			if other.Hash == item.ContentHash && other.Feed == item.FeedURL {
+				state = other
+				break
+			}
+		}
+	}
+	if state.Read != item.Read {
+		state.Read, state.ReadAt = item.Read, now
+	}
+	if state.Starred != item.Starred {
+		state.Starred, state.StarredAt = item.Starred, now
+	}
+	state.Hash, state.Feed = item.ContentHash, item.FeedURL
+	s.items[key] = state
+	return s.save()
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// refresh fetches every feed, or everything from the backend.
+func (u *UI) refresh() {
+	u.refreshFeeds(u.sources)
+}
+
+// refreshSelected fetches just the feed of the selected item, or of the
+// feed selected in the feed list. Backends can only fetch everything.
+func (u *UI) refreshSelected() {
+	if u.backend != nil {
+		u.refresh()
+		return
+	}
+	item, ok := u.actedFeed()
+	if !ok {
+		u.setStatus("Select a feed first")
+		return
+	}
+	for _, source := range u.sources {
+		if source.URL == item.FeedURL {
+			u.refreshFeeds([]FeedSource{source})
+			return
+		}
+	}
+	u.setStatus("[red]" + tview.Escape(CleanString(item.FeedTitle)) + " is no longer in feeds.csv")
+}
+
+// retryFailed fetches the feeds that failed in the last refresh again.
+func (u *UI) retryFailed() {
+	var failed []FeedSource
+	for _, p := range u.fetchErrors {
+		failed = append(failed, p.Source)
+	}
+	if len(failed) == 0 {
+		u.setStatus("No failed feeds to retry")
+		return
+	}
+	u.refreshFeeds(failed)
+}
+
+// refreshFeeds fetches some of the feeds in the background, showing
+// progress in the status line, and replaces their items with the result.
+// With a backend, all items are fetched again.
+func (u *UI) refreshFeeds(sources []FeedSource) {
+	if u.cancelRefresh != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	u.cancelRefresh = cancel
+
+	// Failures of feeds that aren't fetched again stay listed
+	fetching := make(map[string]bool)
+	for _, source := range sources {
+		fetching[source.URL] = true
+	}
+	var kept []fetchProgress
+	for _, p := range u.fetchErrors {
+		if !fetching[p.Source.URL] {
+			kept = append(kept, p)
+		}
+	}
+	u.fetchErrors = kept
+
+	if u.backend != nil {
+		u.setStatus("Fetching items from " + config.BackendURL + "...")
+	} else {
+		u.setStatus(fmt.Sprintf("Fetching 0/%d feeds...", len(sources)))
+	}
+
+	// Each feed's items are shown as soon as it's fetched; known are the
+	// items shown before, to tell which are new.
+	known := u.knownItems()
+	goSafe(func() {
+		var fetched []FeedItem
+		var err error
+		if u.backend != nil {
+			fetched, err = u.backend.Fetch(ctx)
+			fetched = limitPerFeed(fetched, config.MaxItems)
+			prepareSearch(fetched)
+		} else {
+			fetched, err = fetchFeeds(ctx, sources, func(p fetchProgress) {
+				u.state.apply(p.Fetched)
+				prepareSearch(p.Fetched)
+				u.app.QueueUpdateDraw(func() {
+					u.showProgress(p)
+					u.mergeFetched(p.Source, p.Fetched, known)
+				})
+			})
+			if err == nil {
+				u.state.apply(fetched)
+			}
+		}
+
+		cancelled := ctx.Err() != nil
+		cancel()
+		u.app.QueueUpdateDraw(func() {
+			u.cancelRefresh = nil
+			if err != nil {
+				slog.Error("error fetching items", "err", err)
+				u.setStatus("[red]Error fetching items: " + tview.Escape(err.Error()))
+				return
+			}
+
+			items := fetched
+			if u.backend == nil {
+				items = nil
+				for _, item := range u.items {
+					if !fetching[item.FeedURL] {
+						items = append(items, item)
+					}
+				}
+				items = mergeByDate(items, fetched)
+				goWrite(func() {
+					if err := saveItemCache(items); err != nil {
+						slog.Warn("error saving item cache", "err", err)
+					}
+				})
+			}
+			u.findNewItems(known, items)
+			u.setItems(items)
+			if !cancelled {
+				keys := make([]string, len(items))
+				for i, item := range items {
+					keys[i] = itemKey(item)
+				}
+				goWrite(func() {
+					if err := saveSeenItems(keys); err != nil {
+						slog.Warn("error saving session snapshot", "err", err)
+					}
+				})
+			}
+
+			if cancelled {
+				u.setStatus("Refresh cancelled; failed feeds show their saved items")
+			} else {
+				u.setStatus(u.refreshSummary(sources) + u.releaseNotice("; "))
+			}
+			if renames := takeFeedRenames(); len(renames) > 0 {
+				u.askFeedRenames(renames)
+			}
+			if !cancelled {
+				u.syncPodcasts()
+			}
+		})
+		if err == nil && !cancelled {
+			prefetchItems(fetched)
+		}
+	})
+}
+
+// cancelRunningRefresh aborts a refresh in progress, returning false if
+// there is none.
+func (u *UI) cancelRunningRefresh() bool {
+	if u.cancelRefresh == nil {
+		return false
+	}
+	u.cancelRefresh()
+	u.setStatus("Cancelling refresh...")
+	return true
+}
+
+// showProgress updates the status line as a feed finishes fetching.
+func (u *UI) showProgress(p fetchProgress) {
+	u.downloaded[p.Source.URL] = p.Bytes
+	u.sessionBytes += p.Bytes
+	if p.Err != nil {
+		slog.Error("error fetching feed", "url", p.Source.URL, "err", p.Err)
+		u.fetchErrors = append(u.fetchErrors, p)
+	}
+	text := fmt.Sprintf("Fetching %d/%d feeds...", p.Done, p.Total)
+	if n := len(u.fetchErrors); n > 0 {
+		last := u.fetchErrors[n-1].Source
+		text += fmt.Sprintf(" [red]%d failed, last %s[-]", n, tview.Escape(sourceLabel(last)))
+	}
+	u.setStatus(text)
+}
+
+// knownItems returns the keys of the items shown, or nil before the first
+// fetch.
+func (u *UI) knownItems() map[string]bool {
+	if len(u.items) == 0 {
+		return nil
+	}
+	known := make(map[string]bool, len(u.items))
+	for _, item := range u.items {
+		known[itemKey(item)] = true
+	}
+	return known
+}
+
+// findNewItems remembers which of the fetched items weren't shown before
+// the refresh, for the summary and n. On the first fetch every item is new,
+// so none are counted.
+func (u *UI) findNewItems(known map[string]bool, items []FeedItem) {
+	if known == nil {
+		u.newItems = nil
+		return
+	}
+	u.newItems = make(map[string]bool)
+	for _, item := range items {
+		if key := itemKey(item); !known[key] {
+			u.newItems[key] = true
+		}
+	}
+}
+
+// mergeFetched shows the items of a feed that finished fetching in place of
+// its old ones, so the timeline fills in while other feeds are fetched.
+func (u *UI) mergeFetched(source FeedSource, fetched []FeedItem, known map[string]bool) {
+	var kept []FeedItem
+	for _, item := range u.items {
+		if item.FeedURL != source.URL {
+			kept = append(kept, item)
+		}
+	}
+	items := mergeByDate(kept, fetched)
+	u.findNewItems(known, items)
+	u.setItems(items)
+}
+
+// refreshSummary describes a finished refresh of the sources: how many new
+// items arrived in how many feeds, and which feeds failed.
+func (u *UI) refreshSummary(sources []FeedSource) string {
+	feeds := make(map[string]bool)
+	for _, item := range u.items {
+		if u.newItems[itemKey(item)] {
+			feeds[item.FeedTitle] = true
+		}
+	}
+	var text string
+	switch n := len(u.newItems); {
+	case u.newItems == nil:
+	case n == 0:
+		text = "No new items · "
+	default:
+		text = fmt.Sprintf("%s in %s (n to jump to the first) · ", plural(n, "new item"), plural(len(feeds), "feed"))
+	}
+	fetched := "fetched"
+	if text == "" {
+		fetched = "Fetched"
+	}
+	if u.backend != nil {
+		return text + fetched + " " + plural(len(u.items), "item")
+	}
+
+	var bytes int64
+	for _, source := range sources {
+		bytes += u.downloaded[source.URL]
+	}
+	text += fmt.Sprintf("%s %s (%s)", fetched, plural(len(sources), "feed"), humanSize(bytes))
+	if n := len(u.fetchErrors); n > 0 {
+		text += fmt.Sprintf(", [red]%d failed[-] (E to show, F to retry)", n)
+	}
+	return text
+}
+
+// jumpToNew selects the first new item from the last refresh in the
+// current tab, switching to the All tab when this one has none.
+func (u *UI) jumpToNew() {
+	if len(u.newItems) == 0 {
+		u.setStatus("No new items since the last refresh")
+		return
+	}
+	find := func() bool {
+		for row, index := range u.visible {
+			if index >= 0 && u.newItems[itemKey(u.items[index])] {
+				u.table.Select(row, 0)
+				return true
+			}
+		}
+		return false
+	}
+	if find() {
+		return
+	}
+	u.switchTab(0)
+	if !find() {
+		u.setStatus("The new items are hidden by the feed filter or collapsed groups")
+	}
+}
+
+func (u *UI) setStatus(text string) {
+	u.status.SetText(" " + text)
+	if config.ScreenReader {
+		announce(strings.TrimSpace(u.status.GetText(true)))
+	}
+}
+
+// showFetchErrors lists the feeds that failed in the last refresh.
+func (u *UI) showFetchErrors() {
+	view := tview.NewTextView().SetDynamicColors(true).SetWordWrap(true)
+	view.SetBorder(true).SetTitle(" Failed feeds (r to retry, Esc to close) ").SetBorderPadding(0, 0, 1, 1)
+	view.SetBackgroundColor(tcell.ColorDefault)
+	if len(u.fetchErrors) == 0 {
+		view.SetText("No feeds failed in the last refresh.")
+	}
+	for _, p := range u.fetchErrors {
+		fmt.Fprintf(view, "%s\n[red]%s[-]\n\n", tview.Escape(sourceLabel(p.Source)), tview.Escape(p.Err.Error()))
+	}
+
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape || event.Rune() == 'q':
+			u.pages.RemovePage("fetch-errors")
+			return nil
+		case event.Rune() == 'r':
+			u.pages.RemovePage("fetch-errors")
+			u.retryFailed()
+			return nil
+		}
+		return event
+	})
+	u.pages.AddPage("fetch-errors", centered(view, 100, 20), true, true)
+}
+
+// plural formats a count of something, e.g. "1 feed" or "3 feeds".
+func plural(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
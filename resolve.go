@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Attributes holding URLs in item descriptions.
+var urlAttributes = map[string]bool{"href": true, "src": true, "poster": true}
+
+// resolveURL makes ref absolute relative to base. Empty, unparsable and
+// already absolute references are returned unchanged.
+func resolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if base == nil || ref == "" {
+		return ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil || u.IsAbs() {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+// resolveHTML rewrites relative href and src attributes in an HTML
+// fragment so links and images in descriptions point at the feed's site.
+func resolveHTML(base *url.URL, fragment string) string {
+	if base == nil || !strings.ContainsAny(fragment, "<") {
+		return fragment
+	}
+
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), context)
+	if err != nil {
+		return fragment
+	}
+
+	changed := false
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for i, attr := range n.Attr {
+				if urlAttributes[attr.Key] {
+					if resolved := resolveURL(base, attr.Val); resolved != attr.Val {
+						n.Attr[i].Val = resolved
+						changed = true
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	if !changed {
+		return fragment
+	}
+
+	var sb strings.Builder
+	for _, n := range nodes {
+		if err := html.Render(&sb, n); err != nil {
+			return fragment
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	neturl "net/url"
+	"strings"
+	"unicode"
+)
+
+// Schemes a feed-provided URL may have to be handed to another program.
+// Anything else (file:, javascript:, a bare "-flag") is refused so a feed
+// can't open local files or pass options to the browser or player.
+var openableSchemes = map[string]bool{
+	"http": true, "https": true, "magnet": true,
+}
+
+// stripControl removes control characters and bidirectional overrides from
+// feed-provided text before it reaches the terminal. Escape sequences could
+// otherwise retitle the window or redraw the screen, and overrides could
+// make text read differently from what it is. Newlines and tabs are kept.
+func stripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case unicode.IsControl(r):
+			return -1
+		case r >= '\u202a' && r <= '\u202e', r >= '\u2066' && r <= '\u2069':
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// checkURL reports whether a feed-provided URL is safe to pass as an
+// argument to an external program.
+func checkURL(url string) error {
+	if strings.HasPrefix(url, "-") || strings.IndexFunc(url, func(r rune) bool {
+		return unicode.IsControl(r) || unicode.IsSpace(r)
+	}) >= 0 {
+		return fmt.Errorf("refusing to open %q: not a URL", stripControl(url))
+	}
+	parsed, err := neturl.Parse(url)
+	if err != nil || !openableSchemes[strings.ToLower(parsed.Scheme)] {
+		return fmt.Errorf("refusing to open %q: only http, https and magnet links are opened", url)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	dayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+)
+
+// parseSchedule reads the days= or months= option of a feed: names such as
+// "sat sun", or ranges such as "mon-fri" or "sep-may", which wrap around. It
+// returns the indices into names that are included.
+func parseSchedule(spec string, names []string) (map[int]bool, error) {
+	index := func(name string) (int, error) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for i, n := range names {
+			if len(name) >= 3 && strings.HasPrefix(name, n) {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("unknown name %q", name)
+	}
+
+	included := make(map[int]bool)
+	for _, part := range strings.Fields(spec) {
+		from, to, isRange := strings.Cut(part, "-")
+		start, err := index(from)
+		if err != nil {
+			return nil, err
+		}
+		end := start
+		if isRange {
+			if end, err = index(to); err != nil {
+				return nil, err
+			}
+		}
+		for i := start; ; i = (i + 1) % len(names) {
+			included[i] = true
+			if i == end {
+				break
+			}
+		}
+	}
+	if len(included) == 0 {
+		return nil, fmt.Errorf("empty schedule")
+	}
+	return included, nil
+}
+
+// active reports whether the feed is fetched at the given time: it isn't
+// disabled and now falls within its days= and months=.
+func (s FeedSource) active(now time.Time) bool {
+	if s.Disabled {
+		return false
+	}
+	if s.Days != "" {
+		if days, err := parseSchedule(s.Days, dayNames); err == nil && !days[int(now.Weekday())] {
+			return false
+		}
+	}
+	if s.Months != "" {
+		if months, err := parseSchedule(s.Months, monthNames); err == nil && !months[int(now.Month())-1] {
+			return false
+		}
+	}
+	return true
+}
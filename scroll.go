@@ -0,0 +1,54 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// lastScroll is when the last wheel event was handled, for scroll-throttle.
+var lastScroll time.Time
+
+// scroll handles a wheel event over the items, the feed list or the
+// preview, moving scroll-lines lines per tick. Over the lists it moves the
+// selection, or with scroll-mode = viewport only the view. Events closer
+// together than scroll-throttle are dropped.
+func (u *UI) scroll(action tview.MouseAction, x, y int) {
+	now := time.Now()
+	if config.ScrollThrottle > 0 && now.Sub(lastScroll) < config.ScrollThrottle {
+		return
+	}
+	lastScroll = now
+
+	lines := config.ScrollLines
+	if lines <= 0 {
+		lines = 1
+	}
+	if action == tview.MouseScrollUp {
+		lines = -lines
+	}
+
+	if u.showPreview && u.preview.InRect(x, y) {
+		row, column := u.preview.GetScrollOffset()
+		u.preview.ScrollTo(max(row+lines, 0), column)
+		return
+	}
+	if config.ScrollViewport {
+		table := u.table
+		if u.twoPane && u.feeds.InRect(x, y) {
+			table = u.feeds
+		}
+		row, column := table.GetOffset()
+		table.SetOffset(max(row+lines, 0), column)
+		return
+	}
+
+	key := 'j'
+	if lines < 0 {
+		key, lines = 'k', -lines
+	}
+	for i := 0; i < lines; i++ {
+		u.app.QueueEvent(tcell.NewEventKey(tcell.KeyRune, key, tcell.ModNone))
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// queryPrefix starts the URL of a query feed in feeds.csv, a virtual feed of
+// the items matching a search across every subscription.
+const queryPrefix = "query:"
+
+// query returns the search of a query feed.
+func (s FeedSource) query() (string, bool) {
+	if !strings.HasPrefix(s.URL, queryPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(s.URL, queryPrefix)), true
+}
+
+// queryFeeds returns the query feeds from feeds.csv, if there is one.
+func queryFeeds() []FeedSource {
+	sources, err := getFeedSources()
+	if err != nil {
+		return nil
+	}
+	var queries []FeedSource
+	for _, source := range sources {
+		if _, ok := source.query(); ok {
+			queries = append(queries, source)
+		}
+	}
+	return queries
+}
+
+// Search prefixes choosing how a query matches, whatever fuzzy-search in the
+// config says: ~ for fuzzy matching, ' for exact words.
+const (
+	fuzzyPrefix = "~"
+	exactPrefix = "'"
+)
+
+// fieldWeight ranks matches by the field they are in, above any fuzzy score:
+// a match in the title beats one in the feed name, which beats the body.
+const fieldWeight = 1000
+
+// parsedQuery is a search split into folded words, with the matching mode
+// chosen by its prefix or the config.
+type parsedQuery struct {
+	words []string
+	fuzzy bool
+}
+
+func parseQuery(query string) parsedQuery {
+	fuzzy := config.FuzzySearch
+	if strings.HasPrefix(query, fuzzyPrefix) {
+		query, fuzzy = strings.TrimPrefix(query, fuzzyPrefix), true
+	} else if strings.HasPrefix(query, exactPrefix) {
+		query, fuzzy = strings.TrimPrefix(query, exactPrefix), false
+	}
+	return parsedQuery{words: queryWords(foldText(query)), fuzzy: fuzzy}
+}
+
+// queryWords splits a query into words, keeping "quoted phrases" together.
+func queryWords(query string) []string {
+	var words []string
+	for i, part := range strings.Split(query, `"`) {
+		if i%2 == 0 {
+			words = append(words, strings.Fields(part)...)
+		} else if phrase := strings.Join(strings.Fields(part), " "); phrase != "" {
+			words = append(words, phrase)
+		}
+	}
+	return words
+}
+
+// searchText holds the folded text of the item fields a search looks in.
+type searchText struct {
+	title string
+	feed  string // feed name and category
+	body  string
+}
+
+// itemSearchText folds an item's fields for searching. The description and
+// the indexed text of the article are only included with body set, as
+// converting them is comparatively slow.
+func itemSearchText(item FeedItem, body bool) searchText {
+	text := searchText{
+		title: foldText(item.Title),
+		feed:  foldText(item.FeedTitle + " " + item.Category),
+	}
+	if body {
+		description := item.searchBody
+		if description == "" {
+			description = searchable(htmlToText(item.Description.String()))
+		}
+		text.body = description + " " + articleFullText(item.Link)
+	}
+	return text
+}
+
+// prepareSearch folds the descriptions of freshly fetched items for the
+// filter, so the work is done off the interface's goroutine.
+func prepareSearch(items []FeedItem) {
+	for i := range items {
+		items[i].searchBody = searchable(htmlToText(items[i].Description.String()))
+	}
+}
+
+// matchesQuery reports whether every word of query appears in the item's
+// title, feed name or category, ignoring case and accents.
+func matchesQuery(item FeedItem, query string) bool {
+	_, ok := parseQuery(query).score(itemSearchText(item, false))
+	return ok
+}
+
+// score matches every word of the query against the text and returns how
+// well it matched, each word counting where it matched best. Fuzzy matches
+// score higher the more of the word's letters are adjacent and start words.
+// The body is only searched for exact words, since a long text fuzzily
+// contains almost anything.
+func (q parsedQuery) score(text searchText) (int, bool) {
+	total := 0
+	for _, word := range q.words {
+		best := -1
+		if strings.Contains(text.body, word) {
+			best = 0
+		}
+		for tier, field := range []string{text.feed, text.title} {
+			score, ok := q.matchWord(word, field)
+			if ok && (tier+1)*fieldWeight+score > best {
+				best = (tier+1)*fieldWeight + score
+			}
+		}
+		if best < 0 {
+			return 0, false
+		}
+		total += best
+	}
+	return total, true
+}
+
+func (q parsedQuery) matchWord(word, text string) (int, bool) {
+	if !q.fuzzy {
+		return 0, strings.Contains(text, word)
+	}
+	score, _, ok := fuzzyMatch([]rune(word), []rune(text))
+	return score, ok
+}
+
+// fuzzyMatch finds pattern's letters in order in text, like fzf, and scores
+// the shortest stretch of text holding them: each letter scores, with a
+// bonus when it follows the previous one directly or starts a word, and
+// every skipped character costs a point. It also returns where the letters
+// were found.
+func fuzzyMatch(p, t []rune) (int, []int, bool) {
+	if len(p) == 0 {
+		return 0, nil, true
+	}
+
+	// Find the first end of a match, then walk back from it to the
+	// latest start, which gives the tightest match ending there.
+	i, end := 0, -1
+	for j := 0; j < len(t) && end < 0; j++ {
+		if t[j] == p[i] {
+			i++
+			if i == len(p) {
+				end = j
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+	start := end
+	for i, j := len(p)-1, end; i >= 0; j-- {
+		if t[j] == p[i] {
+			start = j
+			i--
+		}
+	}
+
+	score, prev := 0, -2
+	positions := make([]int, 0, len(p))
+	for j := start; j <= end && len(positions) < len(p); j++ {
+		if t[j] != p[len(positions)] {
+			score--
+			continue
+		}
+		score += 16
+		if j == prev+1 {
+			score += 8
+		}
+		if j == 0 || t[j-1] == ' ' {
+			score += 8
+		}
+		prev = j
+		positions = append(positions, j)
+	}
+	return score, positions, true
+}
+
+// highlight escapes text for tview and marks the parts matching the query
+// in the theme's selection colors. Fuzzy queries mark their letters only
+// with fuzzy set, for short fields like the title; otherwise just exact
+// occurrences of the words are marked.
+func highlight(text string, q parsedQuery, fuzzy bool) string {
+	runes := []rune(text)
+	if len(q.words) == 0 || len(runes) == 0 {
+		return tview.Escape(text)
+	}
+
+	// Fold rune by rune to map matches back to the original text
+	var folded []rune
+	var origin []int
+	for i, r := range runes {
+		for _, f := range foldText(string(r)) {
+			folded = append(folded, f)
+			origin = append(origin, i)
+		}
+	}
+
+	marked := make([]bool, len(runes))
+	for _, word := range q.words {
+		w := []rune(word)
+		if q.fuzzy && fuzzy {
+			if _, positions, ok := fuzzyMatch(w, folded); ok {
+				for _, p := range positions {
+					marked[origin[p]] = true
+				}
+			}
+			continue
+		}
+		for start := 0; start+len(w) <= len(folded); start++ {
+			if string(folded[start:start+len(w)]) == word {
+				for k := range w {
+					marked[origin[start+k]] = true
+				}
+			}
+		}
+	}
+
+	theme := currentTheme()
+	var sb strings.Builder
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && marked[j] == marked[i] {
+			j++
+		}
+		segment := tview.Escape(string(runes[i:j]))
+		if marked[i] {
+			sb.WriteString(theme.selected(segment))
+		} else {
+			sb.WriteString(segment)
+		}
+		i = j
+	}
+	return sb.String()
+}
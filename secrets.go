@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// resolveSecrets replaces secret settings that refer to somewhere else with
+// the secret itself, so passwords don't have to be kept in the config.
+func resolveSecrets(cfg *Config) error {
+	secrets := []struct {
+		key   string
+		value *string
+	}{
+		{"backend-password", &cfg.BackendPassword},
+		{"torrent-rpc-secret", &cfg.TorrentRPCSecret},
+		{"gpodder-password", &cfg.GPodderPassword},
+	}
+	for _, s := range secrets {
+		secret, err := resolveSecret(s.key, *s.value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", s.key, err)
+		}
+		*s.value = secret
+	}
+	return nil
+}
+
+// resolveSecret interprets a secret setting:
+//
+//	keyring:NAME  the secret stored under NAME in the system keyring
+//	cmd:COMMAND   the output of a command, e.g. pass or age -d
+//	prompt        asks on the terminal at startup
+//
+// Anything else is the secret itself.
+func resolveSecret(key, value string) (string, error) {
+	switch {
+	case value == "prompt":
+		fmt.Printf("%s: ", key)
+		secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return "", fmt.Errorf("error reading from terminal: %v", err)
+		}
+		return string(secret), nil
+	case strings.HasPrefix(value, "keyring:"):
+		return keyringLookup(strings.TrimPrefix(value, "keyring:"))
+	case strings.HasPrefix(value, "cmd:"):
+		return secretCommand(strings.TrimPrefix(value, "cmd:"))
+	}
+	return value, nil
+}
+
+// secretCommand runs a command that prints a secret. It keeps the terminal
+// so tools like age or gpg can ask for their passphrase.
+func secretCommand(commandLine string) (string, error) {
+	var stdout bytes.Buffer
+	cmd := shellCommand(commandLine)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("error running %q: %v", commandLine, err)
+	}
+	secret, _, _ := strings.Cut(stdout.String(), "\n")
+	return secret, nil
+}
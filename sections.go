@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// rowHeader is a table row that separates items rather than showing one:
+// a date section, or a feed group that can be collapsed.
+type rowHeader struct {
+	label string
+	feed  string // the feed a group header folds, "" for date sections
+	items []int  // the group's items, including collapsed ones
+}
+
+// dateBucket names the section of the timeline an item's date falls in.
+func dateBucket(date, now time.Time) string {
+	localDate, localNow := date.Local(), now.Local()
+	today := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, time.Local)
+	switch {
+	case !localDate.Before(today):
+		return "Today"
+	case !localDate.Before(today.AddDate(0, 0, -1)):
+		return "Yesterday"
+	case !localDate.Before(today.AddDate(0, 0, -6)):
+		return "This week"
+	}
+	return "Older"
+}
+
+// insertDateHeaders returns the sorted rows with a header row, marked -1,
+// wherever the date section changes.
+func insertDateHeaders(rows []int, items []FeedItem, now time.Time) ([]int, map[int]rowHeader) {
+	withHeaders := make([]int, 0, len(rows)+4)
+	headers := make(map[int]rowHeader)
+	last := ""
+	for _, i := range rows {
+		if bucket := dateBucket(items[i].Date, now); bucket != last {
+			headers[len(withHeaders)] = rowHeader{label: bucket}
+			withHeaders = append(withHeaders, -1)
+			last = bucket
+		}
+		withHeaders = append(withHeaders, i)
+	}
+	return withHeaders, headers
+}
+
+// groupByFeed returns the sorted rows grouped under a header row per feed,
+// marked -1, with feeds in the order lessFeed gives. The items of collapsed
+// feeds are left out.
+func groupByFeed(rows []int, items []FeedItem, collapsed map[string]bool, lessFeed func(a, b string) bool) ([]int, map[int]rowHeader) {
+	groups := make(map[string][]int)
+	var feeds []string
+	for _, i := range rows {
+		feed := items[i].FeedTitle
+		if groups[feed] == nil {
+			feeds = append(feeds, feed)
+		}
+		groups[feed] = append(groups[feed], i)
+	}
+	sort.Slice(feeds, func(i, j int) bool {
+		return lessFeed(feeds[i], feeds[j])
+	})
+
+	grouped := make([]int, 0, len(rows)+len(feeds))
+	headers := make(map[int]rowHeader)
+	for _, feed := range feeds {
+		headers[len(grouped)] = rowHeader{label: feed, feed: feed, items: groups[feed]}
+		grouped = append(grouped, -1)
+		if !collapsed[feed] {
+			grouped = append(grouped, groups[feed]...)
+		}
+	}
+	return grouped, headers
+}
+
+// setHeaderRow renders a section header, or a feed group header with its
+// unread count that Enter or space folds.
+func (u *UI) setHeaderRow(row int) {
+	header := u.headers[row]
+	theme := currentTheme()
+	if header.feed == "" {
+		u.table.SetCell(row, 0, tview.NewTableCell(" "+header.label).
+			SetStyle(theme.feedStyle().Bold(true)).
+			SetSelectable(false))
+		return
+	}
+
+	unread := 0
+	for _, i := range header.items {
+		if !u.items[i].Read {
+			unread++
+		}
+	}
+	text := fmt.Sprintf("%s %s (%d unread of %d)", groupMarker(u.collapsed[header.feed]), tview.Escape(CleanString(header.label)), unread, len(header.items))
+	u.table.SetCell(row, 0, tview.NewTableCell(text).
+		SetStyle(theme.feedStyle().Bold(true)))
+}
+
+// toggleGroup collapses or expands a feed group, keeping it selected.
+func (u *UI) toggleGroup(feed string) {
+	u.collapsed[feed] = !u.collapsed[feed]
+	u.render()
+	for row, header := range u.headers {
+		if header.feed == feed {
+			u.table.Select(row, 0)
+		}
+	}
+}
+
+// toggleGrouping switches between the timeline and items grouped by feed,
+// which starts with every group collapsed.
+func (u *UI) toggleGrouping() {
+	selected := u.selected()
+	u.grouped = !u.grouped
+	u.collapsed = make(map[string]bool)
+	if u.grouped {
+		for _, item := range u.items {
+			u.collapsed[item.FeedTitle] = true
+		}
+	}
+	u.render()
+	if u.grouped {
+		u.selectFirst()
+	} else {
+		u.selectItem(selected)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sessionSnapshot records the items a session had seen, so the next one can
+// tell which items arrived since.
+type sessionSnapshot struct {
+	Time  time.Time `json:"time"`
+	Items []string  `json:"items"`
+}
+
+func sessionPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "session.json"), nil
+}
+
+// loadSeenItems returns the keys of the items the previous session had
+// seen, or nil on the first launch.
+func loadSeenItems() map[string]bool {
+	path, err := sessionPath()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var snapshot sessionSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil
+	}
+	seen := make(map[string]bool, len(snapshot.Items))
+	for _, key := range snapshot.Items {
+		seen[key] = true
+	}
+	return seen
+}
+
+// saveSeenItems records the keys of the items this session has seen.
+func saveSeenItems(keys []string) error {
+	path, err := sessionPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(sessionSnapshot{Time: time.Now().UTC(), Items: keys})
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// needsSetup reports whether feeds.csv has not been created yet.
+func needsSetup() bool {
+	path, err := feedsPath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return os.IsNotExist(err)
+}
+
+// runSetup asks a new user for some feeds or an OPML file to import and a
+// theme, then writes feeds.csv and the config. It returns false if the user
+// quit without saving.
+func runSetup() (bool, error) {
+	feeds, err := feedsPath()
+	if err != nil {
+		return false, err
+	}
+	settings, err := configPath()
+	if err != nil {
+		return false, err
+	}
+
+	app := tview.NewApplication()
+	activeApp = app
+
+	intro := tview.NewTextView().SetDynamicColors(true).SetWordWrap(true)
+	intro.SetText(fmt.Sprintf("Welcome to newseum! Subscriptions are kept in %s, one \"Name,URL\" per line, "+
+		"and settings in %s. Pick some starter feeds, paste a few feed URLs or give an OPML file exported from another reader to get started; "+
+		"both files can be edited later.",
+		tview.Escape(feeds), tview.Escape(settings)))
+	status := tview.NewTextView().SetDynamicColors(true)
+
+	themeNames := make([]string, len(themes))
+	initialTheme := 0
+	for i, t := range themes {
+		themeNames[i] = t.Name
+		if t.Name == defaultThemeName() {
+			initialTheme = i
+		}
+	}
+
+	saved := false
+	form := tview.NewForm()
+	for _, b := range feedBundles {
+		form.AddCheckbox(b.Name, false, nil)
+	}
+	form.AddTextArea("Feed URLs", "", 0, 6, 0, nil).
+		AddInputField("OPML file", "", 0, nil, nil).
+		AddDropDown("Theme", themeNames, initialTheme, nil)
+	form.AddButton("Save", func() {
+		urls := form.GetFormItemByLabel("Feed URLs").(*tview.TextArea).GetText()
+		opml := form.GetFormItemByLabel("OPML file").(*tview.InputField).GetText()
+		_, theme := form.GetFormItemByLabel("Theme").(*tview.DropDown).GetCurrentOption()
+		var bundles []feedBundle
+		for _, b := range feedBundles {
+			if form.GetFormItemByLabel(b.Name).(*tview.Checkbox).IsChecked() {
+				bundles = append(bundles, b)
+			}
+		}
+
+		added, err := setupFeeds(urls, opml, bundles)
+		if err != nil {
+			status.SetText("[red]" + tview.Escape(err.Error()))
+			return
+		}
+		if added == 0 {
+			status.SetText("[red]Pick a starter bundle, or add a feed URL or an OPML file")
+			return
+		}
+		if err := writeInitialConfig(theme); err != nil {
+			status.SetText("[red]" + tview.Escape(err.Error()))
+			return
+		}
+		config.Theme = theme
+		saved = true
+		app.Stop()
+	})
+	form.AddButton("Quit", app.Stop)
+	form.SetCancelFunc(app.Stop)
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(intro, 4, 0, false).
+		AddItem(form, 0, 1, true).
+		AddItem(status, 1, 0, false)
+	layout.SetBorder(true).SetTitle(" Set up newseum ").SetBorderPadding(1, 1, 2, 2)
+	intro.SetBackgroundColor(tcell.ColorDefault)
+	status.SetBackgroundColor(tcell.ColorDefault)
+	form.SetBackgroundColor(tcell.ColorDefault)
+	layout.SetBackgroundColor(tcell.ColorDefault)
+
+	if err := app.SetRoot(layout, true).EnableMouse(true).Run(); err != nil {
+		return false, err
+	}
+	return saved, nil
+}
+
+// setupFeeds subscribes to the starter bundles picked, the URLs, one per
+// line, and the feeds in an OPML file, returning how many feeds were added.
+func setupFeeds(urls, opml string, bundles []feedBundle) (int, error) {
+	var sources []FeedSource
+	for _, b := range bundles {
+		sources = append(sources, b.sources()...)
+	}
+	for _, line := range strings.Split(urls, "\n") {
+		url := strings.TrimSpace(line)
+		if url == "" {
+			continue
+		}
+		if err := checkFeedURL(url); err != nil {
+			return 0, err
+		}
+		sources = append(sources, FeedSource{URL: url})
+	}
+	if opml = strings.TrimSpace(opml); opml != "" {
+		imported, err := importOPML(opml)
+		if err != nil {
+			return 0, err
+		}
+		sources = append(sources, imported...)
+	}
+	if len(sources) == 0 {
+		return 0, nil
+	}
+	return appendFeedSources(sources)
+}
+
+// writeInitialConfig creates the config file with the chosen theme, leaving
+// an existing config alone.
+func writeInitialConfig(theme string) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	text := "# newseum settings, one \"key = value\" per line. See the README for every option.\n" +
+		"theme = " + theme + "\n"
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error creating config directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		return fmt.Errorf("error writing config %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package main
+
+import "os/exec"
+
+// Text-to-speech engines tried when tts-command is not configured. Each
+// reads the text to speak on stdin.
+var ttsEngines = [][]string{
+	{"espeak-ng", "--stdin"},
+	{"espeak", "--stdin"},
+	{"say", "-f", "-"},
+}
+
+// shellCommand runs a user-configured command line through the shell.
+func shellCommand(commandLine string) *exec.Cmd {
+	return exec.Command("sh", "-c", commandLine)
+}
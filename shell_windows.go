@@ -0,0 +1,16 @@
+package main
+
+import "os/exec"
+
+// Text-to-speech engines tried when tts-command is not configured. Each
+// reads the text to speak on stdin.
+var ttsEngines = [][]string{
+	{"powershell", "-NoProfile", "-Command",
+		"Add-Type -AssemblyName System.Speech; " +
+			"(New-Object System.Speech.Synthesis.SpeechSynthesizer).Speak([Console]::In.ReadToEnd())"},
+}
+
+// shellCommand runs a user-configured command line through cmd.exe.
+func shellCommand(commandLine string) *exec.Cmd {
+	return exec.Command("cmd", "/C", commandLine)
+}
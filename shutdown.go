@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// shutdownTimeout is how long quitting waits for the writes still in
+// progress, so a hung disk, server or download can't keep newseum open.
+const shutdownTimeout = 5 * time.Second
+
+// pendingWrites counts the background writes started with goWrite.
+var pendingWrites sync.WaitGroup
+
+// goWrite runs f in a new goroutine like goSafe, for saving read state,
+// caches or downloads: newseum waits for it before exiting, so quitting
+// right after marking an item read doesn't lose the change.
+func goWrite(f func()) {
+	pendingWrites.Add(1)
+	goSafe(func() {
+		defer pendingWrites.Done()
+		f()
+	})
+}
+
+// waitForWrites waits up to timeout for the writes started with goWrite
+// and reports whether they all finished.
+func waitForWrites(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		pendingWrites.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// finishWrites is called once the interface has stopped and the terminal
+// is restored; it waits for the pending writes and says so if it gives up.
+func finishWrites() {
+	if !waitForWrites(shutdownTimeout) {
+		fmt.Fprintf(os.Stderr, "newseum: gave up after %v waiting for state and downloads to be saved\n", shutdownTimeout)
+	}
+}
+
+// handleSignals quits on SIGINT, SIGTERM or SIGHUP the way q does, so the
+// terminal leaves raw mode and pending writes are finished instead of the
+// process dying mid-write. A second signal kills it as usual.
+func (u *UI) handleSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	goSafe(func() {
+		<-signals
+		signal.Stop(signals)
+		u.app.QueueUpdate(u.quit)
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// startOptions are the command-line flags choosing what the interface
+// starts with.
+type startOptions struct {
+	noFetch bool
+	// filter adds a "Filter" tab of the items matching it, which is shown
+	// first.
+	filter string
+	// feed is the name of the feed to select in the feed list layout.
+	feed string
+	// view is "timeline", "feeds" or "grouped".
+	view string
+}
+
+func (o startOptions) check() error {
+	switch o.view {
+	case "", "timeline", "feeds", "grouped":
+		return nil
+	}
+	return fmt.Errorf("-view must be timeline, feeds or grouped")
+}
+
+// start sets up the views chosen on the command line and loads the items,
+// fetching them unless -no-fetch was given.
+func (u *UI) start(o startOptions) {
+	for i, t := range u.tabs {
+		if config.StartTab != "" && strings.EqualFold(t.name, config.StartTab) {
+			u.currentTab = i
+		}
+	}
+	if o.filter != "" {
+		u.currentTab = len(u.tabs) - 1
+	}
+	if o.view == "feeds" || o.feed != "" {
+		u.toggleLayout()
+		u.feedFilter = feedEntry{name: o.feed}
+	}
+	if o.view == "grouped" {
+		u.toggleGrouping()
+	}
+	goSafe(func() { cleanupDownloads() })
+	u.checkForUpdate()
+
+	if !o.noFetch {
+		u.refresh()
+		return
+	}
+	var items []FeedItem
+	for _, saved := range loadItemCache() {
+		items = append(items, saved...)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Date.After(items[j].Date)
+	})
+	if u.state != nil {
+		u.state.apply(items)
+	}
+	u.setItems(items)
+	u.setStatus("Showing the items saved by the last fetch")
+}
+
+// feedRow returns the row of the feed list showing the feed filter, matched
+// by name regardless of case, or 0 (all feeds) if it isn't listed.
+func (u *UI) feedRow() int {
+	for row, entry := range u.feedEntries {
+		if entry.query == u.feedFilter.query && strings.EqualFold(entry.name, u.feedFilter.name) {
+			return row
+		}
+	}
+	return 0
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// stateExportVersion is the version of the export format.
+const stateExportVersion = 1
+
+// stateExport is the file newseum state export writes: the flags of every
+// item with, where the item is still cached, what it was, so a backup of
+// starred items means something even after their feeds drop them.
+type stateExport struct {
+	Version  int                     `json:"version"`
+	Exported time.Time               `json:"exported"`
+	Items    map[string]exportedItem `json:"items"`
+}
+
+// exportedItem is an item's flags, whose feed comes from itemState, with
+// its title and link.
+type exportedItem struct {
+	itemState
+	Title string `json:"title,omitempty"`
+	Link  string `json:"link,omitempty"`
+}
+
+// runState carries out newseum state export [FILE] and newseum state import
+// FILE, for moving the read and starred flags to another machine or keeping
+// a backup without a sync backend. Export writes to standard output without
+// a file; import merges, keeping whichever flag changed last.
+func runState(args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	config = cfg
+
+	usage := errors.New("usage: newseum state export [FILE] | newseum state import FILE")
+	if len(args) == 0 {
+		return usage
+	}
+	switch args[0] {
+	case "export":
+		if len(args) > 2 {
+			return usage
+		}
+		out := io.Writer(os.Stdout)
+		if len(args) == 2 && args[1] != "-" {
+			file, err := os.Create(args[1])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			out = file
+		}
+		return exportState(out)
+	case "import":
+		if len(args) != 2 {
+			return usage
+		}
+		imported, err := importState(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported the state of %d items\n", imported)
+		return nil
+	}
+	return usage
+}
+
+// exportState writes the saved flags as JSON.
+func exportState(out io.Writer) error {
+	state, err := loadReadState()
+	if err != nil {
+		return err
+	}
+	cached := make(map[string]FeedItem)
+	for _, items := range loadItemCache() {
+		for _, item := range items {
+			cached[itemKey(item)] = item
+		}
+	}
+
+	export := stateExport{Version: stateExportVersion, Exported: time.Now().UTC(), Items: make(map[string]exportedItem)}
+	state.mu.Lock()
+	for key, s := range state.items {
+		item := cached[key]
+		if s.Feed == "" {
+			s.Feed = item.FeedURL
+		}
+		export.Items[key] = exportedItem{itemState: s, Title: item.Title, Link: item.Link}
+	}
+	state.mu.Unlock()
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(export)
+}
+
+// importState merges an export, or a copy of a state file, into the saved
+// flags and returns how many items it had.
+func importState(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var export stateExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return 0, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	items := make(map[string]itemState)
+	if export.Version != 0 {
+		if export.Version > stateExportVersion {
+			return 0, fmt.Errorf("%s was exported by a newer newseum", path)
+		}
+		for key, item := range export.Items {
+			items[key] = item.itemState
+		}
+	} else if err := json.Unmarshal(data, &items); err != nil {
+		return 0, fmt.Errorf("%s is neither an export nor a state file: %v", path, err)
+	}
+
+	state, err := loadReadState()
+	if err != nil {
+		return 0, err
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	for key, theirs := range items {
+		state.items[key] = mergeItemState(state.items[key], theirs)
+	}
+	return len(items), state.save()
+}
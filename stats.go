@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// showStats lists how much each feed's last download transferred, largest
+// first, with the total for the session, and the quotas of hosts that
+// rate-limit their feeds.
+func (u *UI) showStats() {
+	view := tview.NewTextView().SetDynamicColors(true).SetWrap(false)
+	view.SetBorder(true).SetTitle(" Data used (Esc to close) ").SetBorderPadding(0, 0, 1, 1)
+	view.SetBackgroundColor(tcell.ColorDefault)
+
+	theme := currentTheme()
+	mode := "off"
+	if config.DataSaver {
+		mode = "on"
+	}
+	fmt.Fprintf(view, "Downloaded this session: [::b]%s[::-]\n", humanSize(u.sessionBytes))
+	fmt.Fprintf(view, "%s\n\n", theme.dim("Data saver is "+mode))
+
+	if u.backend != nil {
+		fmt.Fprintln(view, "Feeds are fetched by the backend, which isn't counted per feed.")
+	}
+	sources := append([]FeedSource(nil), u.sources...)
+	sort.SliceStable(sources, func(i, j int) bool {
+		return u.downloaded[sources[i].URL] > u.downloaded[sources[j].URL]
+	})
+	for _, source := range sources {
+		if _, ok := source.query(); ok {
+			continue
+		}
+		fmt.Fprintf(view, "%10s  %s\n", humanSize(u.downloaded[source.URL]), tview.Escape(sourceLabel(source)))
+	}
+
+	if limits := currentRateLimits(); len(limits) > 0 {
+		fmt.Fprintf(view, "\n[::b]Rate limits[::-]\n")
+		for _, limit := range limits {
+			quota := fmt.Sprint(limit.Remaining)
+			if limit.Limit > 0 {
+				quota += fmt.Sprintf("/%d", limit.Limit)
+			}
+			line := fmt.Sprintf("%10s  %s, resets %s", quota, limit.host, formatDate(limit.Reset, u.now))
+			if limit.Remaining == 0 {
+				line = "[red]" + tview.Escape(line) + " (not fetched until then)[-]"
+			} else {
+				line = tview.Escape(line)
+			}
+			fmt.Fprintln(view, line)
+		}
+	}
+
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || event.Rune() == 'q' {
+			u.pages.RemovePage("stats")
+			return nil
+		}
+		return event
+	})
+	u.pages.AddPage("stats", centered(view, 80, 20), true, true)
+}
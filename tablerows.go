@@ -0,0 +1,68 @@
+package main
+
+import "github.com/rivo/tview"
+
+// maxCachedRows is how many rows of cells the item table keeps; a few
+// screens' worth, so scrolling through a long list doesn't keep them all.
+const maxCachedRows = 500
+
+// itemRows is the content of the item table. A row's cells are made by
+// setRow the first time the table draws or looks at the row, so a tab with
+// thousands of items is listed without building cells for all of them.
+type itemRows struct {
+	tview.TableContentReadOnly
+	u     *UI
+	cells map[int][]*tview.TableCell
+}
+
+func newItemRows(u *UI) *itemRows {
+	return &itemRows{u: u, cells: make(map[int][]*tview.TableCell)}
+}
+
+func (r *itemRows) GetCell(row, column int) *tview.TableCell {
+	if row < 0 || row >= len(r.u.visible) {
+		return nil
+	}
+	cells, ok := r.cells[row]
+	if !ok {
+		if len(r.cells) >= maxCachedRows {
+			clear(r.cells) // the rows on screen are made again as they're drawn
+		}
+		r.u.setRow(row)
+		cells = r.cells[row]
+	}
+	if column >= len(cells) {
+		return nil
+	}
+	return cells[column]
+}
+
+func (r *itemRows) GetRowCount() int {
+	return len(r.u.visible)
+}
+
+func (r *itemRows) GetColumnCount() int {
+	if r.u.feedColumn() {
+		return 4
+	}
+	return 3
+}
+
+// SetCell stores a cell set by setRow. setRow fills a row from the first
+// column, which drops what the row showed before.
+func (r *itemRows) SetCell(row, column int, cell *tview.TableCell) {
+	cells := r.cells[row]
+	if column == 0 {
+		cells = cells[:0]
+	}
+	for len(cells) <= column {
+		cells = append(cells, nil)
+	}
+	cells[column] = cell
+	r.cells[row] = cells
+}
+
+// Clear forgets every row, to be made again as they are drawn.
+func (r *itemRows) Clear() {
+	clear(r.cells)
+}
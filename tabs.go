@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+type sortMode int
+
+const (
+	sortNewest sortMode = iota
+	sortOldest
+	sortFeed
+	// sortInterleaved is newest first with the feeds taking turns within
+	// each interleave-window.
+	sortInterleaved
+)
+
+var sortModeNames = []string{"newest first", "oldest first", "by feed", "interleaved"}
+
+// tab is a named view over the items with its own filter, sort order and
+// remembered selection.
+type tab struct {
+	name     string
+	filter   func(FeedItem) bool
+	sort     sortMode
+	selected int // index into items of the selected item, -1 for none
+	// query is the tab's filter typed with /, kept in u.filter while the
+	// tab is shown.
+	query string
+}
+
+// buildTabs returns the All, Unread, Starred and New tabs, one per category
+// and one per saved search from the config. New has the items that aren't
+// among seenBefore, the items of the previous session; it is empty on the
+// first launch, when seenBefore is nil.
+func buildTabs(items []FeedItem, searches []SavedSearch, seenBefore map[string]bool) []*tab {
+	tabs := []*tab{
+		{name: "All", filter: func(FeedItem) bool { return true }},
+		{name: "Unread", filter: func(item FeedItem) bool { return !item.Read }},
+		{name: "Starred", filter: func(item FeedItem) bool { return item.Starred }},
+		{name: "New", filter: func(item FeedItem) bool { return seenBefore != nil && !seenBefore[itemKey(item)] }},
+	}
+
+	seen := make(map[string]bool)
+	var categories []string
+	for _, item := range items {
+		if item.Category != "" && !seen[item.Category] {
+			seen[item.Category] = true
+			categories = append(categories, item.Category)
+		}
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return lessName(categories[i], categories[j])
+	})
+	for _, category := range categories {
+		category := category
+		tabs = append(tabs, &tab{
+			name:   category,
+			filter: func(item FeedItem) bool { return item.Category == category },
+		})
+	}
+
+	for _, search := range searches {
+		query := search.Query
+		tabs = append(tabs, &tab{
+			name:   search.Name,
+			filter: func(item FeedItem) bool { return matchesQuery(item, query) },
+		})
+	}
+
+	for _, t := range tabs {
+		t.selected = -1
+	}
+	return tabs
+}
+
+// sortItems orders the visible item indices according to mode, with feeds
+// in the order lessFeed gives when sorting by feed. items is already newest
+// first, so that order is kept as the tie-breaker.
+func sortItems(visible []int, items []FeedItem, mode sortMode, lessFeed func(a, b string) bool) {
+	switch mode {
+	case sortOldest:
+		sort.SliceStable(visible, func(i, j int) bool {
+			return items[visible[i]].Date.Before(items[visible[j]].Date)
+		})
+	case sortFeed:
+		sort.SliceStable(visible, func(i, j int) bool {
+			return lessFeed(items[visible[i]].FeedTitle, items[visible[j]].FeedTitle)
+		})
+	default:
+		sort.SliceStable(visible, func(i, j int) bool {
+			return items[visible[i]].Date.After(items[visible[j]].Date)
+		})
+	}
+}
+
+// interleaveItems reorders newest-first rows so that within each window of
+// time the feeds take turns, each adding as many items as its weight per
+// round, in the order of their newest item. A feed that posts fifty items at
+// once then doesn't fill the screen.
+func interleaveItems(rows []int, items []FeedItem, window time.Duration, weights map[string]int) {
+	interleaved := make([]int, 0, len(rows))
+	for start := 0; start < len(rows); {
+		bucket := items[rows[start]].Date.Truncate(window)
+		end := start + 1
+		for end < len(rows) && items[rows[end]].Date.Truncate(window).Equal(bucket) {
+			end++
+		}
+
+		var feeds []string
+		queues := make(map[string][]int)
+		for _, row := range rows[start:end] {
+			feed := items[row].FeedURL
+			if _, ok := queues[feed]; !ok {
+				feeds = append(feeds, feed)
+			}
+			queues[feed] = append(queues[feed], row)
+		}
+		for len(interleaved) < end {
+			for _, feed := range feeds {
+				queue := queues[feed]
+				n := min(max(weights[feed], 1), len(queue))
+				interleaved = append(interleaved, queue[:n]...)
+				queues[feed] = queue[n:]
+			}
+		}
+		start = end
+	}
+	copy(rows, interleaved)
+}
+
+// tabBarText renders the tab names with the current one highlighted and
+// the profile in use, if any.
+func tabBarText(tabs []*tab, current int) string {
+	var sb strings.Builder
+	theme := currentTheme()
+	for i, t := range tabs {
+		label := tview.Escape(t.name)
+		if i < 9 {
+			label = fmt.Sprintf("%d %s", i+1, label)
+		}
+		if i == current {
+			sb.WriteString(theme.selected(" "+label+" ") + " ")
+		} else {
+			fmt.Fprintf(&sb, " %s  ", label)
+		}
+	}
+	sb.WriteString(theme.dim("(" + sortModeNames[tabs[current].sort] + ")"))
+	if profile != "" {
+		sb.WriteString("  " + theme.feed("profile: "+tview.Escape(profile)))
+	}
+	return sb.String()
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Theme is a named set of colors for the interface. Colors are tview color
+// names or #rrggbb, usable both for table cells and in preview markup.
+// The attributes are tview attribute letters (b bold, d dim, u underline,
+// r reverse) added to the colors, which monochrome themes rely on alone.
+type Theme struct {
+	Name       string
+	Title      string // unread item titles
+	Feed       string // feed names without a color of their own
+	Dim        string // read items, links and other secondary text
+	SelectedBg string
+	SelectedFg string
+
+	TitleAttrs    string
+	FeedAttrs     string
+	DimAttrs      string
+	SelectedAttrs string
+	// Mono leaves every color at the terminal's default, including feed
+	// colors and the borders.
+	Mono bool
+}
+
+var themes = []Theme{
+	{Name: "default", Title: "red", Feed: "green", Dim: "gray", SelectedBg: "white", SelectedFg: "black"},
+	{Name: "light", Title: "maroon", Feed: "darkgreen", Dim: "gray", SelectedBg: "navy", SelectedFg: "white"},
+	{Name: "gruvbox", Title: "#fb4934", Feed: "#b8bb26", Dim: "#928374", SelectedBg: "#fabd2f", SelectedFg: "#282828"},
+	// Bright colors on a dark background, with bold titles.
+	{Name: "high-contrast", Title: "#ffff00", Feed: "#00ffff", Dim: "#c0c0c0", SelectedBg: "#ffffff", SelectedFg: "#000000",
+		TitleAttrs: "b"},
+	// The Okabe-Ito palette, which stays distinct with the common forms of
+	// color blindness; no red against green.
+	{Name: "colorblind", Title: "#e69f00", Feed: "#56b4e9", Dim: "gray", SelectedBg: "#f0e442", SelectedFg: "#000000"},
+	{Name: "mono", Title: "-", Feed: "-", Dim: "-", SelectedBg: "-", SelectedFg: "-",
+		TitleAttrs: "b", DimAttrs: "d", SelectedAttrs: "r", Mono: true},
+}
+
+// findTheme returns the theme with the given name.
+func findTheme(name string) (Theme, bool) {
+	for _, t := range themes {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Theme{}, false
+}
+
+// defaultThemeName is the theme used when the config doesn't name one:
+// mono when NO_COLOR is set (https://no-color.org), else default.
+func defaultThemeName() string {
+	if os.Getenv("NO_COLOR") != "" {
+		return "mono"
+	}
+	return "default"
+}
+
+// currentTheme returns the theme chosen in the config, or the default.
+func currentTheme() Theme {
+	if t, ok := findTheme(config.Theme); ok {
+		return t
+	}
+	t, _ := findTheme(defaultThemeName())
+	return t
+}
+
+// applyTheme sets the colors tview draws borders and forms with; monochrome
+// themes leave them at the terminal's default.
+func applyTheme() {
+	if !currentTheme().Mono {
+		return
+	}
+	tview.Styles = tview.Theme{
+		PrimitiveBackgroundColor:    tcell.ColorDefault,
+		ContrastBackgroundColor:     tcell.ColorDefault,
+		MoreContrastBackgroundColor: tcell.ColorDefault,
+		BorderColor:                 tcell.ColorDefault,
+		TitleColor:                  tcell.ColorDefault,
+		GraphicsColor:               tcell.ColorDefault,
+		PrimaryTextColor:            tcell.ColorDefault,
+		SecondaryTextColor:          tcell.ColorDefault,
+		TertiaryTextColor:           tcell.ColorDefault,
+		InverseTextColor:            tcell.ColorDefault,
+		ContrastSecondaryTextColor:  tcell.ColorDefault,
+	}
+}
+
+// paint wraps text in markup for a color and attributes, turning the
+// attributes off again after it.
+func paint(color, attrs, text string) string {
+	if attrs == "" {
+		return "[" + color + "]" + text + "[-]"
+	}
+	return "[" + color + "::" + attrs + "]" + text + "[-::" + strings.ToUpper(attrs) + "]"
+}
+
+// feed marks text up as a feed name.
+func (t Theme) feed(text string) string {
+	return paint(t.Feed, t.FeedAttrs, text)
+}
+
+// dim marks text up as secondary.
+func (t Theme) dim(text string) string {
+	return paint(t.Dim, t.DimAttrs, text)
+}
+
+// selected marks text up like the selected row, for search matches and
+// the current tab.
+func (t Theme) selected(text string) string {
+	closing := "[-:-]"
+	if t.SelectedAttrs != "" {
+		closing = "[-:-:" + strings.ToUpper(t.SelectedAttrs) + "]"
+	}
+	return "[" + t.SelectedFg + ":" + t.SelectedBg + ":" + t.SelectedAttrs + "]" + text + closing
+}
+
+// style is the cell style for a color and attributes.
+func style(color, attrs string) tcell.Style {
+	s := tcell.StyleDefault.Foreground(tcell.GetColor(color))
+	for _, a := range attrs {
+		switch a {
+		case 'b':
+			s = s.Bold(true)
+		case 'd':
+			s = s.Dim(true)
+		case 'i':
+			s = s.Italic(true)
+		case 'u':
+			s = s.Underline(true)
+		case 'r':
+			s = s.Reverse(true)
+		}
+	}
+	return s
+}
+
+// titleStyle is the cell style of unread titles.
+func (t Theme) titleStyle() tcell.Style {
+	return style(t.Title, t.TitleAttrs)
+}
+
+// feedStyle is the cell style of feed names without a color of their own.
+func (t Theme) feedStyle() tcell.Style {
+	return style(t.Feed, t.FeedAttrs)
+}
+
+// dimStyle is the cell style of read items and secondary text.
+func (t Theme) dimStyle() tcell.Style {
+	return style(t.Dim, t.DimAttrs)
+}
+
+// selectedStyle is the style of the selected row in lists and tables.
+func (t Theme) selectedStyle() tcell.Style {
+	return style(t.SelectedFg, t.SelectedAttrs).Background(tcell.GetColor(t.SelectedBg))
+}
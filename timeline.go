@@ -0,0 +1,30 @@
+package main
+
+import "sort"
+
+// sortByDate puts items newest first, keeping the order of those with the
+// same date.
+func sortByDate(items []FeedItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Date.After(items[j].Date)
+	})
+}
+
+// mergeByDate merges two lists of items that are each newest first into a
+// new one, so a feed's items can be added to the timeline as they arrive
+// without sorting all of it again. Of items with the same date, those of a
+// come first.
+func mergeByDate(a, b []FeedItem) []FeedItem {
+	merged := make([]FeedItem, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if b[0].Date.After(a[0].Date) {
+			merged = append(merged, b[0])
+			b = b[1:]
+		} else {
+			merged = append(merged, a[0])
+			a = a[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
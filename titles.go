@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rivo/tview"
+)
+
+// storedTitle is the title a feed without a name in feeds.csv is shown
+// under. Declined is a newer title of the feed the user chose not to use,
+// so they aren't asked about it again.
+type storedTitle struct {
+	Title    string `json:"title"`
+	Declined string `json:"declined,omitempty"`
+}
+
+// feedRename is a feed that started calling itself something else.
+type feedRename struct {
+	URL string
+	Old string
+	New string
+}
+
+// feedTitles remembers the titles of unnamed feeds, so a feed renaming
+// itself doesn't silently move its items to a new name in the feed list,
+// groups and saved searches.
+var feedTitles struct {
+	sync.Mutex
+	loaded  bool
+	titles  map[string]storedTitle
+	renames []feedRename
+}
+
+func feedTitlesPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "titles.json"), nil
+}
+
+// resolveFeedTitle returns the title to show for an unnamed feed: the one
+// first seen, with any change recorded for the user to accept.
+func resolveFeedTitle(url, title string) string {
+	feedTitles.Lock()
+	defer feedTitles.Unlock()
+	if !feedTitles.loaded {
+		feedTitles.titles = make(map[string]storedTitle)
+		if path, err := feedTitlesPath(); err == nil {
+			if data, err := os.ReadFile(path); err == nil {
+				json.Unmarshal(data, &feedTitles.titles)
+			}
+		}
+		feedTitles.loaded = true
+	}
+
+	stored, ok := feedTitles.titles[url]
+	switch {
+	case title == "":
+		return stored.Title
+	case !ok:
+		feedTitles.titles[url] = storedTitle{Title: title}
+		saveFeedTitles()
+		return title
+	case title != stored.Title && title != stored.Declined:
+		slog.Info("feed renamed itself", "url", url, "old", stored.Title, "new", title)
+		feedTitles.renames = append(feedTitles.renames, feedRename{URL: url, Old: stored.Title, New: title})
+	}
+	return stored.Title
+}
+
+// takeFeedRenames returns the renames found since it was last called.
+func takeFeedRenames() []feedRename {
+	feedTitles.Lock()
+	defer feedTitles.Unlock()
+	renames := feedTitles.renames
+	feedTitles.renames = nil
+	return renames
+}
+
+// settleFeedRenames stores the new titles when adopt is set, or remembers
+// them as declined.
+func settleFeedRenames(renames []feedRename, adopt bool) {
+	feedTitles.Lock()
+	defer feedTitles.Unlock()
+	for _, rename := range renames {
+		stored := feedTitles.titles[rename.URL]
+		if adopt {
+			stored = storedTitle{Title: rename.New}
+		} else {
+			stored.Declined = rename.New
+		}
+		feedTitles.titles[rename.URL] = stored
+	}
+	saveFeedTitles()
+}
+
+// saveFeedTitles writes the stored titles; the caller holds the lock.
+func saveFeedTitles() {
+	path, err := feedTitlesPath()
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0o755)
+	}
+	var data []byte
+	if err == nil {
+		data, err = json.MarshalIndent(feedTitles.titles, "", "\t")
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0o644)
+	}
+	if err != nil {
+		slog.Warn("error saving feed titles", "err", err)
+	}
+}
+
+// askFeedRenames asks whether to show feeds that renamed themselves under
+// their new titles.
+func (u *UI) askFeedRenames(renames []feedRename) {
+	var sb strings.Builder
+	sb.WriteString("Some feeds changed their titles:\n\n")
+	for _, rename := range renames {
+		fmt.Fprintf(&sb, "%s → %s\n", CleanString(rename.Old), CleanString(rename.New))
+	}
+	sb.WriteString("\nShow them under the new titles?")
+
+	modal := tview.NewModal().
+		SetText(tview.Escape(sb.String())).
+		AddButtons([]string{"Use new titles", "Keep old titles"}).
+		SetDoneFunc(func(button int, _ string) {
+			u.pages.RemovePage("renames")
+			adopt := button == 0
+			settleFeedRenames(renames, adopt)
+			if !adopt {
+				return
+			}
+			items := append([]FeedItem(nil), u.items...)
+			for _, rename := range renames {
+				for i := range items {
+					if items[i].FeedURL == rename.URL && items[i].FeedTitle == rename.Old {
+						items[i].FeedTitle = rename.New
+					}
+				}
+			}
+			u.setItems(items)
+		})
+	u.pages.AddPage("renames", modal, true, true)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// torrentURL returns the item's magnet link or .torrent file, or "".
+func torrentURL(item FeedItem) string {
+	for _, enclosure := range item.Enclosures {
+		if isTorrent(enclosure.URL) || enclosure.Type == "application/x-bittorrent" {
+			return enclosure.URL
+		}
+	}
+	if isTorrent(item.Link) {
+		return item.Link
+	}
+	return ""
+}
+
+func isTorrent(url string) bool {
+	lowerURL := strings.ToLower(url)
+	return strings.HasPrefix(lowerURL, "magnet:") || strings.HasSuffix(lowerURL, ".torrent")
+}
+
+// sendTorrent hands a magnet link or torrent URL to the torrent-client from
+// the config: either "aria2:" followed by an aria2 JSON-RPC endpoint, or a
+// command such as "transmission-remote -a" that gets the URL appended.
+// Without one the system's magnet/torrent handler is used.
+func sendTorrent(url string) error {
+	if err := checkURL(url); err != nil {
+		return err
+	}
+	client := config.TorrentClient
+	switch {
+	case client == "":
+		return openDefault(url)
+	case strings.HasPrefix(client, "aria2:"):
+		return aria2AddURI(strings.TrimPrefix(client, "aria2:"), url)
+	default:
+		fields := strings.Fields(client)
+		output, err := exec.Command(fields[0], append(fields[1:], url)...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("error adding torrent: %v: %s", err, strings.TrimSpace(string(output)))
+		}
+		slog.Info("added torrent", "url", url, "output", strings.TrimSpace(string(output)))
+		return nil
+	}
+}
+
+// aria2AddURI queues a download through aria2's JSON-RPC interface.
+func aria2AddURI(endpoint, url string) error {
+	params := []any{[]string{url}}
+	if config.TorrentRPCSecret != "" {
+		params = append([]any{"token:" + config.TorrentRPCSecret}, params...)
+	}
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      "newseum",
+		"method":  "aria2.addUri",
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error contacting aria2: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil && resp.StatusCode == http.StatusOK {
+		return fmt.Errorf("error reading aria2 response: %v", err)
+	}
+	if result.Error != nil {
+		return fmt.Errorf("aria2: %s", result.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("aria2: %s", resp.Status)
+	}
+	return nil
+}
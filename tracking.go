@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// trackingParams are the query parameters cleanLink removes unless
+// keep-params names them. A trailing * matches any suffix.
+var trackingParams = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "gbraid", "wbraid", "msclkid",
+	"yclid", "igshid", "mc_cid", "mc_eid", "_hsenc", "_hsmi", "mkt_tok",
+	"oly_anon_id", "oly_enc_id", "vero_id", "wt_mc", "ref_src", "__s",
+}
+
+// cleanLink removes tracking parameters from a link's query, so shared
+// links are clean and the same article linked with different campaign tags
+// is recognized as one. The rest of the query keeps its order and encoding.
+func cleanLink(link string) string {
+	if config.KeepTrackers || !strings.Contains(link, "?") {
+		return link
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.RawQuery == "" {
+		return link
+	}
+	var kept []string
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		name, _, _ := strings.Cut(param, "=")
+		if name, err := url.QueryUnescape(name); err == nil && isTrackingParam(name) {
+			continue
+		}
+		kept = append(kept, param)
+	}
+	u.RawQuery = strings.Join(kept, "&")
+	u.ForceQuery = false
+	return u.String()
+}
+
+// isTrackingParam reports whether a query parameter is stripped.
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	if matchParam(config.KeepParams, name) {
+		return false
+	}
+	return matchParam(trackingParams, name) || matchParam(config.StripParams, name)
+}
+
+func matchParam(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ampPrefix matches the Google AMP viewer and AMP cache paths that wrap
+// another site's page, up to the wrapped host.
+var ampPrefix = regexp.MustCompile(`^/(?:amp/|[cvi]/)(s/)?`)
+
+// desktopLink rewrites AMP and mobile links to the desktop page they stand
+// for: Google AMP viewer and AMP cache links to the original, amp. and m.
+// hosts to the main one, and /amp paths and amp query flags away.
+func desktopLink(link string) string {
+	if config.KeepAMP {
+		return link
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "www.google.com" && strings.HasPrefix(u.Path, "/amp/") || strings.HasSuffix(host, ".cdn.ampproject.org") {
+		if m := ampPrefix.FindStringSubmatch(u.Path); m != nil {
+			scheme := "http"
+			if m[1] != "" {
+				scheme = "https"
+			}
+			inner, err := url.Parse(scheme + "://" + u.Path[len(m[0]):])
+			if err != nil || inner.Host == "" {
+				return link
+			}
+			inner.RawQuery = u.RawQuery
+			inner.Fragment = u.Fragment
+			u = inner
+		}
+	}
+
+	labels := strings.Split(u.Host, ".")
+	for i := 0; i < len(labels)-2; i++ {
+		switch strings.ToLower(labels[i]) {
+		case "amp", "m", "mobile":
+			labels = append(labels[:i], labels[i+1:]...)
+			i--
+		}
+	}
+	u.Host = strings.Join(labels, ".")
+
+	original := u.Path
+	switch {
+	case strings.HasSuffix(u.Path, "/amp") || strings.HasSuffix(u.Path, "/amp/"):
+		u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/amp")
+		if u.Path == "" {
+			u.Path = "/"
+		}
+	case strings.Contains(u.Path, "/amp/"):
+		u.Path = strings.Replace(u.Path, "/amp/", "/", 1)
+	case strings.HasSuffix(u.Path, ".amp.html"):
+		u.Path = strings.TrimSuffix(u.Path, ".amp.html") + ".html"
+	case strings.HasSuffix(u.Path, ".amp"):
+		u.Path = strings.TrimSuffix(u.Path, ".amp")
+	}
+	if u.Path != original {
+		u.RawPath = ""
+	}
+
+	if u.RawQuery != "" {
+		var kept []string
+		for _, param := range strings.Split(u.RawQuery, "&") {
+			name, value, _ := strings.Cut(param, "=")
+			if name == "amp" || name == "outputType" && value == "amp" {
+				continue
+			}
+			kept = append(kept, param)
+		}
+		u.RawQuery = strings.Join(kept, "&")
+	}
+	return u.String()
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ttrssAllArticles = -4
+	ttrssAllFeeds    = -3
+	ttrssPageSize    = 200
+	ttrssMaxItems    = 1000
+
+	ttrssFieldStarred = 0
+	ttrssFieldUnread  = 2
+)
+
+// TTRSSBackend talks to the Tiny Tiny RSS JSON API. baseURL is the tt-rss
+// installation, e.g. https://example.com/tt-rss.
+type TTRSSBackend struct {
+	apiURL   string
+	user     string
+	password string
+
+	mutex     sync.Mutex // guards sessionID
+	sessionID string
+}
+
+func newTTRSSBackend(baseURL, user, password string) (*TTRSSBackend, error) {
+	if baseURL == "" || user == "" {
+		return nil, fmt.Errorf("the ttrss backend needs backend-url and backend-user")
+	}
+
+	b := &TTRSSBackend{
+		apiURL:   strings.TrimSuffix(baseURL, "/") + "/api/",
+		user:     user,
+		password: password,
+	}
+	if err := b.login(context.Background()); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *TTRSSBackend) login(ctx context.Context) error {
+	content, err := b.call(ctx, map[string]any{
+		"op":       "login",
+		"user":     b.user,
+		"password": b.password,
+	})
+	if err != nil {
+		return fmt.Errorf("error logging in to %s: %v", b.apiURL, err)
+	}
+
+	var session struct {
+		SessionID string `json:"session_id"`
+	}
+	if err := json.Unmarshal(content, &session); err != nil || session.SessionID == "" {
+		return fmt.Errorf("error logging in to %s: no session in response", b.apiURL)
+	}
+
+	b.mutex.Lock()
+	b.sessionID = session.SessionID
+	b.mutex.Unlock()
+	return nil
+}
+
+// call sends one API request and returns the "content" of the response.
+func (b *TTRSSBackend) call(ctx context.Context, request map[string]any) (json.RawMessage, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", b.apiURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+
+	var result struct {
+		Status  int             `json:"status"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if result.Status != 0 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(result.Content, &apiErr)
+		return nil, fmt.Errorf("api error: %s", apiErr.Error)
+	}
+	return result.Content, nil
+}
+
+// sessionCall is call with the session ID filled in. An expired session is
+// renewed once.
+func (b *TTRSSBackend) sessionCall(ctx context.Context, request map[string]any) (json.RawMessage, error) {
+	for attempt := 0; ; attempt++ {
+		b.mutex.Lock()
+		request["sid"] = b.sessionID
+		b.mutex.Unlock()
+
+		content, err := b.call(ctx, request)
+		if err == nil || attempt == 1 || !strings.Contains(err.Error(), "NOT_LOGGED_IN") {
+			return content, err
+		}
+		if err := b.login(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+type ttrssHeadline struct {
+	ID          int         `json:"id"`
+	Title       string      `json:"title"`
+	Link        string      `json:"link"`
+	Updated     int64       `json:"updated"`
+	FeedID      json.Number `json:"feed_id"`
+	FeedTitle   string      `json:"feed_title"`
+	Unread      bool        `json:"unread"`
+	Marked      bool        `json:"marked"`
+	Content     string      `json:"content"`
+	Attachments []struct {
+		ContentURL  string `json:"content_url"`
+		ContentType string `json:"content_type"`
+	} `json:"attachments"`
+}
+
+// feedCategories maps feed IDs to the title of the category they're in.
+func (b *TTRSSBackend) feedCategories(ctx context.Context) (map[string]string, error) {
+	content, err := b.sessionCall(ctx, map[string]any{"op": "getCategories"})
+	if err != nil {
+		return nil, err
+	}
+	var categories []struct {
+		ID    json.Number `json:"id"`
+		Title string      `json:"title"`
+	}
+	if err := json.Unmarshal(content, &categories); err != nil {
+		return nil, err
+	}
+	titles := make(map[string]string)
+	for _, category := range categories {
+		titles[category.ID.String()] = category.Title
+	}
+
+	content, err = b.sessionCall(ctx, map[string]any{"op": "getFeeds", "cat_id": ttrssAllFeeds})
+	if err != nil {
+		return nil, err
+	}
+	var feeds []struct {
+		ID    json.Number `json:"id"`
+		CatID json.Number `json:"cat_id"`
+	}
+	if err := json.Unmarshal(content, &feeds); err != nil {
+		return nil, err
+	}
+	feedCategories := make(map[string]string)
+	for _, feed := range feeds {
+		feedCategories[feed.ID.String()] = titles[feed.CatID.String()]
+	}
+	return feedCategories, nil
+}
+
+func (b *TTRSSBackend) Fetch(ctx context.Context) ([]FeedItem, error) {
+	categories, err := b.feedCategories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching categories: %v", err)
+	}
+
+	var items []FeedItem
+	for skip := 0; skip < ttrssMaxItems; skip += ttrssPageSize {
+		content, err := b.sessionCall(ctx, map[string]any{
+			"op":                  "getHeadlines",
+			"feed_id":             ttrssAllArticles,
+			"limit":               ttrssPageSize,
+			"skip":                skip,
+			"view_mode":           "all_articles",
+			"show_content":        true,
+			"include_attachments": true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error fetching headlines: %v", err)
+		}
+
+		var headlines []ttrssHeadline
+		if err := json.Unmarshal(content, &headlines); err != nil {
+			return nil, fmt.Errorf("error parsing headlines: %v", err)
+		}
+		for _, h := range headlines {
+			item := h.feedItem()
+			item.Category = categories[h.FeedID.String()]
+			items = append(items, item)
+		}
+		if len(headlines) < ttrssPageSize {
+			break
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Date.After(items[j].Date)
+	})
+	return items, nil
+}
+
+func (h ttrssHeadline) feedItem() FeedItem {
+	item := FeedItem{
+		ID:          strconv.Itoa(h.ID),
+		Title:       h.Title,
+		Date:        time.Unix(h.Updated, 0).UTC(),
+		FeedTitle:   h.FeedTitle,
+		Link:        cleanLink(h.Link),
+		Description: newItemBody(h.Content),
+		Read:        !h.Unread,
+		Starred:     h.Marked,
+	}
+	for _, attachment := range h.Attachments {
+		if item.AudioURL == "" && strings.HasPrefix(attachment.ContentType, "audio/") {
+			item.AudioURL = attachment.ContentURL
+		}
+		item.Enclosures = append(item.Enclosures, Enclosure{URL: attachment.ContentURL, Type: attachment.ContentType})
+	}
+	return item
+}
+
+func (b *TTRSSBackend) updateArticle(item FeedItem, field int, value bool) error {
+	mode := 0
+	if value {
+		mode = 1
+	}
+	_, err := b.sessionCall(context.Background(), map[string]any{
+		"op":          "updateArticle",
+		"article_ids": item.ID,
+		"mode":        mode,
+		"field":       field,
+	})
+	if err != nil {
+		return fmt.Errorf("error updating %s: %v", item.Title, err)
+	}
+	return nil
+}
+
+func (b *TTRSSBackend) MarkRead(item FeedItem, read bool) error {
+	return b.updateArticle(item, ttrssFieldUnread, !read)
+}
+
+func (b *TTRSSBackend) SetStarred(item FeedItem, starred bool) error {
+	return b.updateArticle(item, ttrssFieldStarred, starred)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+var (
+	speechMutex sync.Mutex
+	speech      *childProcess
+	// speechPending is the item of a reading whose article is still being
+	// fetched; toggling again meanwhile clears it, which cancels the reading.
+	speechPending *FeedItem
+)
+
+// ttsCommand returns the text-to-speech command, which reads the text to
+// speak on stdin. tts-command from the config is run through the shell so
+// it can be a pipeline (e.g. piper into aplay).
+func ttsCommand() (*exec.Cmd, error) {
+	if config.TTSCommand != "" {
+		return shellCommand(config.TTSCommand), nil
+	}
+
+	for _, engine := range ttsEngines {
+		if path, err := exec.LookPath(engine[0]); err == nil {
+			return exec.Command(path, engine[1:]...), nil
+		}
+	}
+	return nil, fmt.Errorf("no text-to-speech engine found; set tts-command in the config")
+}
+
+// articleText returns the readable text of an item's article, falling back
+// to the feed-provided description when the page can't be extracted.
+func articleText(item FeedItem) string {
+	if item.Link != "" {
+		text, err := extractArticle(item.Link)
+		if err == nil && text != "" {
+			return text
+		}
+		if text := savedArticleText(item.Link); text != "" {
+			return text
+		}
+	}
+	return htmlToText(item.Description.String())
+}
+
+// toggleSpeech reads the item aloud, or stops the current reading if one is
+// already in progress.
+func toggleSpeech(item FeedItem) error {
+	speechMutex.Lock()
+	if speech != nil {
+		current := speech
+		speech = nil
+		speechMutex.Unlock()
+		return current.kill()
+	}
+	if speechPending != nil {
+		speechPending = nil
+		speechMutex.Unlock()
+		return nil
+	}
+	pending := &item
+	speechPending = pending
+	speechMutex.Unlock()
+
+	cmd, err := ttsCommand()
+	if err == nil {
+		cmd.Stdin = strings.NewReader(CleanString(item.Title) + ".\n\n" + articleText(item))
+	}
+
+	speechMutex.Lock()
+	defer speechMutex.Unlock()
+	if speechPending != pending {
+		return nil
+	}
+	speechPending = nil
+	if err != nil {
+		return err
+	}
+	p, err := startProcess(cmd)
+	if err != nil {
+		return fmt.Errorf("error starting text-to-speech: %v", err)
+	}
+	speech = p
+
+	goSafe(func() {
+		<-p.done
+		speechMutex.Lock()
+		if speech == p {
+			speech = nil
+		}
+		speechMutex.Unlock()
+	})
+	return nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// textPage is a text view for reading item text. It lays the text out
+// itself according to the preview settings: in a column of at most
+// preview-width cells centered in the view, with paragraph-spacing blank
+// lines between paragraphs, and optionally hyphenated and justified.
+type textPage struct {
+	*tview.TextView
+	padding int
+	source  string
+	width   int
+}
+
+// newTextPage returns a text page with padding cells of space on the left
+// and right.
+func newTextPage(padding int) *textPage {
+	page := &textPage{TextView: tview.NewTextView().SetDynamicColors(true).SetWordWrap(true), padding: padding}
+	page.SetBorderPadding(0, 0, padding, padding)
+	return page
+}
+
+// typesetting reports whether any of the preview settings are in use.
+func typesetting() bool {
+	return config.PreviewWidth > 0 || config.ParagraphSpacing != 1 || config.Hyphenate || config.Justify
+}
+
+// SetText sets the text to show, which is laid out when the page is drawn.
+func (p *textPage) SetText(text string) *tview.TextView {
+	p.source = text
+	p.width = 0
+	return p.TextView.SetText(text)
+}
+
+// Draw lays the text out for the current width and draws it.
+func (p *textPage) Draw(screen tcell.Screen) {
+	if typesetting() {
+		_, _, width, _ := p.GetRect()
+		width -= 2 + 2*p.padding // border and padding
+		margin := 0
+		if config.PreviewWidth > 0 && width > config.PreviewWidth {
+			margin = (width - config.PreviewWidth) / 2
+			width = config.PreviewWidth
+		}
+		p.SetBorderPadding(0, 0, p.padding+margin, p.padding+margin)
+		if width > 0 && width != p.width {
+			p.width = width
+			row, column := p.GetScrollOffset()
+			p.TextView.SetText(typeset(p.source, width))
+			p.ScrollTo(row, column)
+		}
+	}
+	p.TextView.Draw(screen)
+}
+
+// typeset wraps tview markup to width cells. Paragraphs, separated by blank
+// lines, get config.ParagraphSpacing blank lines between them; long words
+// that would leave a line short are hyphenated; and with config.Justify
+// every full line of a paragraph is padded with spaces to the full width.
+func typeset(text string, width int) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			for i := 0; i < config.ParagraphSpacing; i++ {
+				lines = append(lines, "")
+			}
+			blank = false
+		}
+		lines = append(lines, wrapWords(words, width)...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrapWords fills lines of at most width cells with words.
+func wrapWords(words []string, width int) []string {
+	var lines []string
+	var line []string
+	lineWidth := 0
+	for len(words) > 0 {
+		word := words[0]
+		wordWidth := tview.TaggedStringWidth(word)
+		space := 0
+		if len(line) > 0 {
+			space = 1
+		}
+		if lineWidth+space+wordWidth <= width {
+			line = append(line, word)
+			lineWidth += space + wordWidth
+			words = words[1:]
+			continue
+		}
+		if config.Hyphenate {
+			if head, tail, ok := hyphenate(word, width-lineWidth-space); ok {
+				line = append(line, head)
+				lines = append(lines, justifyLine(line, lineWidth+space+tview.TaggedStringWidth(head), width))
+				line, lineWidth = nil, 0
+				words[0] = tail
+				continue
+			}
+		}
+		if len(line) == 0 {
+			// A word wider than the column is left for the view to break.
+			lines = append(lines, word)
+			words = words[1:]
+			continue
+		}
+		lines = append(lines, justifyLine(line, lineWidth, width))
+		line, lineWidth = nil, 0
+	}
+	if len(line) > 0 {
+		lines = append(lines, strings.Join(line, " "))
+	}
+	return lines
+}
+
+// hyphenate splits a plain word so that its head and a hyphen fit in room
+// cells, keeping at least three letters on each side. Words with markup or
+// that aren't all letters are not split.
+func hyphenate(word string, room int) (string, string, bool) {
+	runes := []rune(word)
+	if strings.ContainsRune(word, '[') || room < 4 || len(runes) < 7 || tview.TaggedStringWidth(word) != len(runes) {
+		return "", "", false
+	}
+	for _, r := range runes {
+		if !unicode.IsLetter(r) {
+			return "", "", false
+		}
+	}
+	cut := room - 1
+	if cut > len(runes)-3 {
+		cut = len(runes) - 3
+	}
+	if cut < 3 {
+		return "", "", false
+	}
+	return string(runes[:cut]) + "-", string(runes[cut:]), true
+}
+
+// justifyLine joins words into a line, spreading them over width cells when
+// justification is on. lineWidth is the width with single spaces.
+func justifyLine(words []string, lineWidth, width int) string {
+	if !config.Justify || len(words) < 2 || lineWidth >= width {
+		return strings.Join(words, " ")
+	}
+	gaps := len(words) - 1
+	extra := width - lineWidth
+	var sb strings.Builder
+	for i, word := range words {
+		sb.WriteString(word)
+		if i < gaps {
+			spaces := 1 + extra/gaps
+			if i < extra%gaps {
+				spaces++
+			}
+			sb.WriteString(strings.Repeat(" ", spaces))
+		}
+	}
+	return sb.String()
+}
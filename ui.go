@@ -0,0 +1,1261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"github.com/rivo/tview"
+)
+
+// UI is the item browser. The table shows a subset of items, chosen by the
+// current filters, through the visible index list so that state changes
+// made in one view are kept when switching to another.
+type UI struct {
+	app     *tview.Application
+	pages   *tview.Pages
+	root    *tview.Flex
+	layout  *tview.Flex
+	tabBar  *tview.TextView
+	feeds   *tview.Table
+	table   *tview.Table
+	preview *textPage
+	status  *tview.TextView
+	backend Backend
+	// state remembers read and starred flags when there is no backend.
+	state *readState
+
+	items   []FeedItem
+	visible []int
+	now     time.Time
+
+	// sources are the feeds.csv subscriptions, when there is no backend;
+	// fetchErrors are the feeds that failed in the last refresh.
+	sources     []FeedSource
+	fetchErrors []fetchProgress
+	// downloaded is how many bytes each feed's last download transferred,
+	// by feed URL, and sessionBytes is the total since startup.
+	downloaded   map[string]int64
+	sessionBytes int64
+	// newItems are the keys of the items the last refresh added, and
+	// seenBefore those of the items the previous session had seen.
+	newItems   map[string]bool
+	seenBefore map[string]bool
+	// cancelRefresh stops the refresh in progress, if any.
+	cancelRefresh context.CancelFunc
+	// newRelease is a newer release found by update-check that hasn't been
+	// told yet.
+	newRelease string
+
+	tabs       []*tab
+	currentTab int
+	// pendingG is set after g, which starts the gt/gT tab commands;
+	// beforeG is the selection to restore if one of them follows.
+	pendingG bool
+	beforeG  int
+	// filter narrows the current tab to the items matching it, best
+	// matches first. searchTexts caches the folded text of the items it
+	// has looked at, by index.
+	filter      string
+	searchTexts map[int]searchText
+	// filterMatches are the items that matched filterMatchesFor when the
+	// list was last rendered. narrowing is set while a filter that only
+	// adds to that one is applied, so just those items need looking at.
+	filterMatches    []int
+	filterMatchesFor string
+	narrowing        bool
+
+	// twoPane shows the feed list beside the items; feedFilter is the entry
+	// selected there.
+	twoPane     bool
+	feedFilter  feedEntry
+	feedEntries []feedEntry
+	queries     []FeedSource
+
+	showPreview bool
+	// titleScroll is how many cells of every title are scrolled out of
+	// view to the left.
+	titleScroll int
+	// sectionHeaders separates the timeline into Today, Yesterday, This
+	// week and Older; grouped shows the items under a header per feed
+	// instead, without those in collapsed feeds. headers describes the
+	// header rows, which are -1 in visible.
+	sectionHeaders bool
+	grouped        bool
+	collapsed      map[string]bool
+	headers        map[int]rowHeader
+	// hiddenLanguages are the languages whose items aren't shown.
+	hiddenLanguages map[string]bool
+}
+
+// newUI creates the interface with no items; refresh fetches them.
+func newUI(sources []FeedSource, backend Backend, state *readState) *UI {
+	u := &UI{
+		app:     tview.NewApplication(),
+		pages:   tview.NewPages(),
+		layout:  tview.NewFlex(),
+		tabBar:  tview.NewTextView().SetDynamicColors(true).SetWrap(false),
+		feeds:   tview.NewTable().SetSelectable(true, false),
+		table:   tview.NewTable().SetSelectable(true, false),
+		preview: newTextPage(1),
+		status:  tview.NewTextView().SetDynamicColors(true).SetWrap(false),
+		backend: backend,
+		state:   state,
+		sources: sources,
+		now:     time.Now().UTC(), // Use UTC for consistency
+		tabs:    buildTabs(nil, config.Searches, nil),
+		queries: queryFeeds(),
+
+		downloaded: make(map[string]int64),
+		seenBefore: loadSeenItems(),
+
+		sectionHeaders:  config.SectionHeaders,
+		hiddenLanguages: make(map[string]bool),
+	}
+	for _, language := range config.HideLanguages {
+		u.hiddenLanguages[normalizeLanguage(language)] = true
+	}
+	activeApp = u.app
+
+	u.tabBar.SetBackgroundColor(tcell.ColorDefault)
+	u.status.SetBackgroundColor(tcell.ColorDefault)
+	u.table.SetContent(newItemRows(u))
+	u.table.SetBackgroundColor(tcell.ColorDefault)
+	u.table.SetSelectedStyle(currentTheme().selectedStyle())
+	u.feeds.SetBackgroundColor(tcell.ColorDefault)
+	u.feeds.SetSelectedStyle(currentTheme().selectedStyle())
+	u.feeds.SetBorders(false).SetBorder(true).SetBorderPadding(0, 0, 1, 1)
+	u.preview.SetBackgroundColor(tcell.ColorDefault)
+	u.preview.SetBorder(true)
+
+	u.table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape && !u.clearFilter() && !u.cancelRunningRefresh() {
+			u.quit()
+		}
+	}).SetInputCapture(u.handleItemKey)
+	u.table.SetSelectedFunc(func(row, column int) {
+		u.openItem(row)
+	})
+	u.table.SetSelectionChangedFunc(func(row, column int) {
+		u.updatePreview()
+		u.announceSelection()
+	})
+
+	u.feeds.SetInputCapture(u.handleFeedKey)
+	u.feeds.SetSelectionChangedFunc(func(row, column int) {
+		if row >= 0 && row < len(u.feedEntries) && u.feedEntries[row] != u.feedFilter {
+			u.feedFilter = u.feedEntries[row]
+			u.render()
+			u.selectFirst()
+		}
+	})
+	u.feeds.SetSelectedFunc(func(row, column int) {
+		u.app.SetFocus(u.table)
+	})
+
+	u.preview.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || event.Key() == tcell.KeyTab {
+			u.app.SetFocus(u.table)
+			return nil
+		}
+		return event
+	})
+
+	u.app.SetMouseCapture(func(event *tcell.EventMouse, action tview.MouseAction) (*tcell.EventMouse, tview.MouseAction) {
+		x, y := event.Position()
+		onItems := !u.overlayShown()
+		switch {
+		case onItems && action == tview.MouseLeftDoubleClick && u.table.InRect(x, y):
+			row, _ := u.table.GetSelection()
+			u.openItem(row)
+			return nil, 0
+		case onItems && action == tview.MouseLeftDoubleClick && u.twoPane && u.feeds.InRect(x, y):
+			u.app.SetFocus(u.table)
+			return nil, 0
+		case onItems && action == tview.MouseLeftClick && u.status.InRect(x, y) && u.filter != "":
+			u.showFilter()
+			return nil, 0
+		case onItems && (action == tview.MouseScrollDown || action == tview.MouseScrollUp):
+			u.scroll(action, x, y)
+			return nil, 0 // Consume the event
+		case action == tview.MouseScrollDown:
+			u.app.QueueEvent(tcell.NewEventKey(tcell.KeyRune, 'j', tcell.ModNone))
+			return nil, 0
+		case action == tview.MouseScrollUp:
+			u.app.QueueEvent(tcell.NewEventKey(tcell.KeyRune, 'k', tcell.ModNone))
+			return nil, 0
+		}
+		return event, action
+	})
+
+	if config.ScreenReader {
+		u.app.SetAfterDrawFunc(u.placeCursor)
+	}
+
+	u.layoutPanes()
+	u.render()
+	u.selectFirst()
+
+	u.root = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(u.tabBar, 1, 0, false).
+		AddItem(u.layout, 0, 1, true).
+		AddItem(u.status, 1, 0, false)
+	u.pages.AddPage("items", u.root, true, true)
+	return u
+}
+
+func (u *UI) run() error {
+	u.handleSignals()
+	return u.app.SetRoot(u.pages, true).EnableMouse(!config.NoMouse).Run()
+}
+
+// overlayShown reports whether a dialog or overlay is over the items.
+func (u *UI) overlayShown() bool {
+	front, _ := u.pages.GetFrontPage()
+	return front != "items"
+}
+
+// quit cancels any refresh in progress and leaves the interface.
+func (u *UI) quit() {
+	if u.cancelRefresh != nil {
+		u.cancelRefresh()
+	}
+	u.app.Stop()
+}
+
+// layoutPanes arranges the single timeline or the feed list and items side
+// by side, depending on twoPane.
+func (u *UI) layoutPanes() {
+	u.layout.Clear()
+	if u.twoPane {
+		u.renderFeeds()
+		u.layout.AddItem(u.feeds, 40, 0, false)
+	}
+	if u.showPreview {
+		u.layout.AddItem(u.table, 0, 2, true)
+		u.layout.AddItem(u.preview, 0, 1, false)
+		u.updatePreview()
+	} else {
+		u.layout.AddItem(u.table, 0, 1, true)
+	}
+	u.app.SetFocus(u.table)
+}
+
+// updatePreview shows the selected item in the preview pane.
+func (u *UI) updatePreview() {
+	if !u.showPreview {
+		return
+	}
+	index := u.selected()
+	if index < 0 {
+		u.preview.SetText("")
+		return
+	}
+	item := u.items[index]
+	u.preview.SetText(previewText(item, u.now, u.filter)).ScrollToBeginning()
+	if _, ok := cachedArticleText(item.Link); ok || item.Link == "" {
+		return
+	}
+	// Reading and parsing the saved article is too slow to do while keys
+	// move the selection, so it is shown once it's ready
+	goSafe(func() {
+		if savedArticleText(item.Link) == "" {
+			return
+		}
+		u.app.QueueUpdateDraw(func() {
+			if index := u.selected(); index >= 0 && u.items[index].Link == item.Link {
+				row, column := u.preview.GetScrollOffset()
+				u.preview.SetText(previewText(u.items[index], u.now, u.filter)).ScrollTo(row, column)
+			}
+		})
+	})
+}
+
+// render rebuilds the item table from the current tab and feed filter.
+func (u *UI) render() {
+	t := u.tabs[u.currentTab]
+	u.visible = u.visible[:0]
+	scores := make(map[int]int)
+	query := parseQuery(u.filter)
+	consider := func(i int) {
+		item := u.items[i]
+		if u.twoPane && !u.feedFilter.matches(item) {
+			return
+		}
+		if !t.filter(item) || u.hidden(item) {
+			return
+		}
+		if u.filter != "" {
+			score, ok := query.score(u.searchText(i))
+			if !ok {
+				return
+			}
+			scores[i] = score
+		}
+		u.visible = append(u.visible, i)
+	}
+	if u.narrowing && narrowsFilter(u.filterMatchesFor, u.filter) {
+		for _, i := range u.filterMatches {
+			consider(i)
+		}
+	} else {
+		for i := range u.items {
+			consider(i)
+		}
+	}
+	u.filterMatches, u.filterMatchesFor = nil, u.filter
+	if u.filter != "" {
+		u.filterMatches = slices.Clone(u.visible)
+	}
+	sortItems(u.visible, u.items, t.sort, u.lessFeed())
+	if t.sort == sortInterleaved {
+		weights := make(map[string]int)
+		for _, source := range u.sources {
+			weights[source.URL] = source.Weight
+		}
+		interleaveItems(u.visible, u.items, config.InterleaveWindow, weights)
+	}
+	if u.filter != "" {
+		sort.SliceStable(u.visible, func(i, j int) bool {
+			return scores[u.visible[i]] > scores[u.visible[j]]
+		})
+	}
+	u.headers = nil
+	if u.grouped {
+		u.visible, u.headers = groupByFeed(u.visible, u.items, u.collapsed, u.lessFeed())
+	} else if u.sectionHeaders && t.sort != sortFeed && t.sort != sortInterleaved && u.filter == "" {
+		u.visible, u.headers = insertDateHeaders(u.visible, u.items, u.now)
+	}
+
+	u.table.Clear() // rows are made as they are drawn
+	u.tabBar.SetText(tabBarText(u.tabs, u.currentTab))
+}
+
+// tooOld reports whether an item is hidden by the max-age setting. Starred
+// items and items without a date are always shown.
+func (u *UI) tooOld(item FeedItem) bool {
+	return config.MaxAge > 0 && !item.Starred && !item.Date.IsZero() && u.now.Sub(item.Date) > config.MaxAge
+}
+
+// addItems adds newly fetched items, updating the tabs for any new category.
+func (u *UI) addItems(items []FeedItem) {
+	if u.state != nil {
+		u.state.apply(items)
+	}
+	u.setItems(append(u.items[:len(u.items):len(u.items)], items...))
+}
+
+// setItems replaces the items, rebuilding the tabs for the categories they
+// have. Each tab keeps its sort order and its selection when the selected
+// item is still there.
+func (u *UI) setItems(items []FeedItem) {
+	key := func(index int) string {
+		if index < 0 || index >= len(u.items) {
+			return ""
+		}
+		return itemKey(u.items[index])
+	}
+	u.tabs[u.currentTab].selected = u.selected()
+	u.tabs[u.currentTab].query = u.filter
+	old := make(map[string]*tab)
+	selectedKeys := make(map[string]string)
+	for _, t := range u.tabs {
+		old[t.name] = t
+		selectedKeys[t.name] = key(t.selected)
+	}
+	current := u.tabs[u.currentTab].name
+
+	u.items = items
+	u.searchTexts = nil
+	indices := make(map[string]int, len(items))
+	for i, item := range items {
+		indices[itemKey(item)] = i
+	}
+	find := func(key string) int {
+		if i, ok := indices[key]; ok && key != "" {
+			return i
+		}
+		return -1
+	}
+
+	u.tabs = buildTabs(u.items, config.Searches, u.seenBefore)
+	u.currentTab = 0
+	for i, t := range u.tabs {
+		t.selected = -1
+		if prev, ok := old[t.name]; ok {
+			t.sort, t.selected, t.query = prev.sort, find(selectedKeys[t.name]), prev.query
+		}
+		if t.name == current {
+			u.currentTab = i
+		}
+	}
+	u.filter = u.tabs[u.currentTab].query
+	if u.twoPane {
+		u.renderFeeds()
+		row := u.feedRow()
+		u.feedFilter = u.feedEntries[row]
+		u.feeds.Select(row, 0)
+	}
+	u.render()
+	u.selectItem(u.tabs[u.currentTab].selected)
+}
+
+// selectItem moves the selection to the row showing items[index], or to
+// the first row if it isn't visible.
+func (u *UI) selectItem(index int) {
+	for row, i := range u.visible {
+		if i == index {
+			u.table.Select(row, 0)
+			return
+		}
+	}
+	u.selectFirst()
+}
+
+// selectFirst selects the first item, scrolling up to show any section
+// header above it. Feed group headers can be selected themselves.
+func (u *UI) selectFirst() {
+	row := 0
+	for row < len(u.visible)-1 && u.visible[row] < 0 && u.headers[row].feed == "" {
+		row++
+	}
+	u.table.Select(row, 0)
+	u.table.ScrollToBeginning()
+}
+
+// switchTab shows another tab, remembering the selection and filter of
+// the one left.
+func (u *UI) switchTab(i int) {
+	if i < 0 || i >= len(u.tabs) || i == u.currentTab {
+		return
+	}
+	u.tabs[u.currentTab].selected = u.selected()
+	u.tabs[u.currentTab].query = u.filter
+	previous := u.filter
+	u.currentTab = i
+	u.filter = u.tabs[i].query
+	u.render()
+	u.selectItem(u.tabs[i].selected)
+	if u.filter != "" {
+		u.setStatus("Filter: " + tview.Escape(u.filter) + " (/ to change, Esc to clear)")
+	} else if previous != "" {
+		u.setStatus("")
+	}
+}
+
+// cycleSort changes the sort order of the current tab.
+func (u *UI) cycleSort() {
+	t := u.tabs[u.currentTab]
+	selected := u.selected()
+	t.sort = (t.sort + 1) % sortMode(len(sortModeNames))
+	u.render()
+	u.selectItem(selected)
+}
+
+// feedEntry is a row of the feed list: a feed, a query feed, or all feeds
+// when both fields are empty.
+type feedEntry struct {
+	name  string
+	query string
+}
+
+func (e feedEntry) matches(item FeedItem) bool {
+	if e.query != "" {
+		return matchesQuery(item, e.query)
+	}
+	return e.name == "" || item.FeedTitle == e.name
+}
+
+// renderFeeds lists each feed with its unread count, preceded by an entry
+// for all feeds and the query feeds.
+func (u *UI) renderFeeds() {
+	unread := make(map[string]int)
+	total := 0
+	for _, item := range u.items {
+		count := unread[item.FeedTitle]
+		if !item.Read && !u.hidden(item) {
+			count++
+			total++
+		}
+		unread[item.FeedTitle] = count
+	}
+
+	// Disabled feeds and those out of their schedule are listed greyed out,
+	// even without items.
+	paused := make(map[string]string)
+	titles := u.feedTitles()
+	now := time.Now()
+	for _, source := range u.sources {
+		if _, ok := source.query(); ok || source.active(now) {
+			continue
+		}
+		name := sourceTitle(source, titles)
+		paused[name] = "off schedule"
+		if source.Disabled {
+			paused[name] = "disabled"
+		}
+		unread[name] += 0
+	}
+
+	var names []string
+	for name := range unread {
+		names = append(names, name)
+	}
+	less := u.lessFeed()
+	sort.Slice(names, func(i, j int) bool {
+		return less(names[i], names[j])
+	})
+
+	entries := []feedEntry{{}}
+	for _, source := range u.queries {
+		query, _ := source.query()
+		entries = append(entries, feedEntry{name: source.Name, query: query})
+	}
+	for _, name := range names {
+		entries = append(entries, feedEntry{name: name})
+	}
+	u.feedEntries = entries
+
+	u.feeds.Clear()
+	for row, entry := range entries {
+		label, count := "All feeds", total
+		switch {
+		case entry.query != "":
+			label, count = tview.Escape(CleanString(entry.name)), 0
+			for _, item := range u.items {
+				if !item.Read && !u.hidden(item) && entry.matches(item) {
+					count++
+				}
+			}
+		case entry.name != "":
+			label, count = tview.Escape(CleanString(entry.name)), unread[entry.name]
+		}
+		labelCell := tview.NewTableCell(label).SetExpansion(1).SetMaxWidth(30)
+		if reason, ok := paused[entry.name]; ok && entry.query == "" {
+			labelCell.SetText(label + " (" + reason + ")").SetStyle(currentTheme().dimStyle())
+		}
+		u.feeds.SetCell(row, 0, labelCell)
+		countCell := tview.NewTableCell(fmt.Sprint(count)).SetAlign(tview.AlignRight)
+		if count == 0 {
+			countCell.SetStyle(currentTheme().dimStyle())
+		}
+		u.feeds.SetCell(row, 1, countCell)
+	}
+}
+
+// setRow renders the item shown at the given table row. Read items are
+// dimmed, starred ones marked with an asterisk and updated ones with a
+// tilde.
+func (u *UI) setRow(row int) {
+	if u.visible[row] < 0 {
+		u.setHeaderRow(row)
+		return
+	}
+	item := u.items[u.visible[row]]
+	dateStr := " " + formatDate(item.Date, u.now)
+	marker := " "
+	switch {
+	case item.Starred:
+		marker = "*"
+	case !item.UpdatedAt.IsZero():
+		marker = "~"
+	}
+	titleStr := FormatString(marker+titleText(CleanString(item.Title), u.titleScroll), titleWidth)
+	feedName := CleanString(item.FeedTitle)
+	if item.FeedGlyph != "" && !config.ScreenReader {
+		feedName = item.FeedGlyph + " " + feedName
+	}
+	feedStr := FormatString(" "+feedName, 25)
+
+	theme := currentTheme()
+	titleStyle, feedStyle := theme.titleStyle(), theme.feedStyle().Foreground(feedColorOf(item))
+	if item.Read {
+		titleStyle, feedStyle = theme.dimStyle(), theme.dimStyle()
+	}
+	title := tview.NewTableCell(tview.Escape(titleStr)).SetStyle(titleStyle)
+	feed := tview.NewTableCell(tview.Escape(feedStr)).SetStyle(feedStyle)
+
+	col := 0
+	if u.feedColumn() {
+		u.table.SetCell(row, col, feed)
+		col++
+	}
+	u.table.SetCell(row, col, title)
+	stats := formatDuration(item.Duration)
+	if stats == "" {
+		stats = formatCounts(item)
+	}
+	duration := tview.NewTableCell(stats).SetAlign(tview.AlignRight).
+		SetStyle(theme.dimStyle())
+	u.table.SetCell(row, col+1, duration)
+	u.table.SetCellSimple(row, col+2, dateStr)
+}
+
+// titleWidth is the width of the title column, and titleScrollStep how far
+// Left and Right scroll the titles.
+const (
+	titleWidth      = 75
+	titleScrollStep = 20
+)
+
+// titleText is the part of a title shown in its column: scrolled left by
+// scroll cells, with an ellipsis where text is cut off on either side.
+func titleText(title string, scroll int) string {
+	if scroll > 0 {
+		if runewidth.StringWidth(title) <= scroll {
+			return ""
+		}
+		title = runewidth.TruncateLeft(title, scroll, "…")
+	}
+	return runewidth.Truncate(title, titleWidth-1, "…")
+}
+
+// scrollTitles scrolls every title by delta cells, up to where the longest
+// visible one ends.
+func (u *UI) scrollTitles(delta int) {
+	longest := 0
+	for _, i := range u.visible {
+		if i >= 0 {
+			longest = max(longest, runewidth.StringWidth(CleanString(u.items[i].Title)))
+		}
+	}
+	// The column has room for titleWidth-2 cells after the marker and the
+	// leading ellipsis.
+	scroll := min(max(u.titleScroll+delta, 0), max(longest-(titleWidth-2), 0))
+	if scroll == u.titleScroll {
+		return
+	}
+	u.titleScroll = scroll
+	u.table.Clear()
+}
+
+// feedColumn reports whether the item table has a column for the feed,
+// which is otherwise shown in the feed list or the group headers.
+func (u *UI) feedColumn() bool {
+	return !u.twoPane && !u.grouped
+}
+
+// Colors assigned to feeds by auto-feed-colors, chosen to be readable on
+// both dark and light terminals.
+var autoFeedColors = []tcell.Color{
+	tcell.ColorGreen, tcell.ColorTeal, tcell.ColorOlive, tcell.ColorPurple,
+	tcell.ColorNavy, tcell.ColorFuchsia, tcell.ColorDarkCyan, tcell.ColorDarkOrange,
+	tcell.ColorLime, tcell.ColorAqua, tcell.ColorCornflowerBlue, tcell.ColorGoldenrod,
+}
+
+// feedColorOf returns the color for an item's feed name: the feed's color
+// option, a color derived from the feed name with auto-feed-colors, or the
+// theme's feed color. Monochrome themes use none of them.
+func feedColorOf(item FeedItem) tcell.Color {
+	if currentTheme().Mono {
+		return tcell.ColorDefault
+	}
+	if item.FeedColor != "" {
+		return tcell.GetColor(item.FeedColor)
+	}
+	if config.AutoFeedColors {
+		h := fnv.New32a()
+		h.Write([]byte(item.FeedTitle))
+		return autoFeedColors[h.Sum32()%uint32(len(autoFeedColors))]
+	}
+	return tcell.GetColor(currentTheme().Feed)
+}
+
+// selected returns the index into items of the selected row, or -1.
+func (u *UI) selected() int {
+	row, _ := u.table.GetSelection()
+	if row < 0 || row >= len(u.visible) {
+		return -1
+	}
+	return u.visible[row]
+}
+
+// refreshRow redraws the row showing items[index] after its state changed.
+func (u *UI) refreshRow(index int) {
+	for row, i := range u.visible {
+		if i == index {
+			u.setRow(row)
+			break
+		}
+	}
+	for row, header := range u.headers {
+		if header.feed != "" && header.feed == u.items[index].FeedTitle {
+			u.setRow(row) // update the group's unread count
+		}
+	}
+	if u.twoPane {
+		row, _ := u.feeds.GetSelection()
+		u.renderFeeds()
+		u.feeds.Select(row, 0)
+	}
+}
+
+// markRead updates an item's read state locally and on the backend, or in
+// the state file when there is no backend.
+func (u *UI) markRead(index int, read bool) {
+	if u.items[index].Read == read {
+		return
+	}
+	u.items[index].Read = read
+	u.refreshRow(index)
+	item := u.items[index]
+	if read {
+		uploadGPodderAction("play", item, item.AudioURL)
+	}
+	if u.backend != nil {
+		goWrite(func() {
+			if err := u.backend.MarkRead(item, read); err != nil {
+				slog.Error("error syncing read state", "err", err)
+			}
+		})
+	} else {
+		u.saveState(item)
+	}
+}
+
+func (u *UI) toggleStarred(index int) {
+	u.items[index].Starred = !u.items[index].Starred
+	u.refreshRow(index)
+	item := u.items[index]
+	archiveStarred(item)
+	if u.backend != nil {
+		goWrite(func() {
+			if err := u.backend.SetStarred(item, item.Starred); err != nil {
+				slog.Error("error syncing starred state", "err", err)
+			}
+		})
+	} else {
+		u.saveState(item)
+	}
+}
+
+func (u *UI) saveState(item FeedItem) {
+	if u.state == nil {
+		return
+	}
+	goWrite(func() {
+		if err := u.state.update(item); err != nil {
+			slog.Error("error saving state file", "err", err)
+		}
+	})
+}
+
+func (u *UI) openItem(row int) {
+	if row < 0 || row >= len(u.visible) {
+		return
+	}
+	if header, ok := u.headers[row]; ok {
+		if header.feed != "" {
+			u.toggleGroup(header.feed)
+		}
+		return
+	}
+	index := u.visible[row]
+	item := u.items[index]
+
+	var url string
+	if item.AudioURL != "" {
+		url = item.AudioURL
+	} else {
+		url = desktopLink(cleanLink(item.Link))
+	}
+	source, _ := u.sourceOf(item)
+	if source.Open == "reader" {
+		u.showReader(item)
+		u.markRead(index, true)
+		u.recordOpened(item)
+		return
+	}
+	err := openWith(source.Open, url)
+	if errors.Is(err, errNoBrowser) {
+		u.showReader(item)
+	} else if err != nil {
+		slog.Error("error opening browser", "url", url, "err", err)
+	}
+	u.markRead(index, true)
+	if err == nil || errors.Is(err, errNoBrowser) {
+		u.recordOpened(item)
+	}
+}
+
+func (u *UI) handleItemKey(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyTab && u.twoPane {
+		u.app.SetFocus(u.feeds)
+		return nil
+	}
+
+	if u.pendingG {
+		u.pendingG = false
+		switch event.Rune() {
+		case 't':
+			u.tabs[u.currentTab].selected = u.beforeG
+			u.selectItem(u.beforeG)
+			u.switchTab((u.currentTab + 1) % len(u.tabs))
+			return nil
+		case 'T':
+			u.tabs[u.currentTab].selected = u.beforeG
+			u.selectItem(u.beforeG)
+			u.switchTab((u.currentTab + len(u.tabs) - 1) % len(u.tabs))
+			return nil
+		}
+	}
+
+	if r := event.Rune(); r >= '1' && r <= '9' {
+		u.switchTab(int(r - '1'))
+		return nil
+	}
+	switch {
+	case event.Key() == tcell.KeyRight || event.Rune() == '>':
+		u.scrollTitles(titleScrollStep)
+		return nil
+	case event.Key() == tcell.KeyLeft || event.Rune() == '<':
+		u.scrollTitles(-titleScrollStep)
+		return nil
+	}
+
+	switch event.Rune() {
+	case 'q':
+		u.quit()
+		return nil
+	case '/':
+		u.showFilter()
+		return nil
+	case 'W':
+		u.openSite()
+		return nil
+	case 'Y':
+		u.copyFeedURL()
+		return nil
+	case 'r':
+		u.refreshSelected()
+		return nil
+	case 'R':
+		u.refresh()
+		return nil
+	case 'n':
+		u.jumpToNew()
+		return nil
+	case 'g':
+		u.pendingG = true
+		u.beforeG = u.selected()
+		u.selectFirst()
+	case 'o':
+		u.cycleSort()
+		return nil
+	case 'z':
+		u.toggleGrouping()
+		return nil
+	case 'O':
+		u.showOpenUnread()
+		return nil
+	case ' ':
+		row, _ := u.table.GetSelection()
+		if header, ok := u.headers[row]; ok && header.feed != "" {
+			u.toggleGroup(header.feed)
+			return nil
+		}
+	case 'H':
+		selected := u.selected()
+		u.sectionHeaders = !u.sectionHeaders
+		u.render()
+		u.selectItem(selected)
+		return nil
+	case 'G':
+		u.table.Select(len(u.visible)-1, 0)
+		u.table.ScrollToEnd()
+	case 'L':
+		u.toggleLayout()
+		return nil
+	case 't':
+		if index := u.selected(); index >= 0 {
+			item := u.items[index]
+			goSafe(func() {
+				if err := toggleSpeech(item); err != nil {
+					slog.Error("error reading article aloud", "err", err)
+				}
+			})
+		}
+		return nil
+	case 'P':
+		u.showProcesses()
+		return nil
+	case 'A':
+		u.showAddFeed()
+		return nil
+	case 'E':
+		u.showFetchErrors()
+		return nil
+	case 'S':
+		u.showStats()
+		return nil
+	case 'D':
+		u.showDownloads()
+		return nil
+	case 'F':
+		u.retryFailed()
+		return nil
+	case 'v':
+		u.showQuickLook()
+		return nil
+	case 'c':
+		u.showChanges()
+		return nil
+	case 'K':
+		u.archiveSelected()
+		return nil
+	case 'I':
+		u.showArchive()
+		return nil
+	case 'N':
+		u.showLanguages()
+		return nil
+	case 'h':
+		u.showHistory()
+		return nil
+	case 'p':
+		u.showPreview = !u.showPreview
+		u.layoutPanes()
+		return nil
+	case 'e':
+		if index := u.selected(); index >= 0 {
+			u.showEnclosures(index)
+		}
+		return nil
+	case 'a':
+		if index := u.selected(); index >= 0 && u.items[index].Link != "" {
+			url := archiveURL(u.items[index].Link)
+			if err := openURL(url); err != nil {
+				slog.Error("error opening archived copy", "url", url, "err", err)
+			}
+			u.markRead(index, true)
+			u.recordOpened(u.items[index])
+		}
+		return nil
+	case 'f':
+		if index := u.selected(); index >= 0 {
+			u.showLinks(index)
+		}
+		return nil
+	case 'i':
+		if index := u.selected(); index >= 0 {
+			u.showImages(index)
+		}
+		return nil
+	case 'b':
+		if index := u.selected(); index >= 0 {
+			if url := torrentURL(u.items[index]); url != "" {
+				goSafe(func() {
+					if err := sendTorrent(url); err != nil {
+						slog.Error("error sending torrent", "url", url, "err", err)
+					}
+				})
+				u.markRead(index, true)
+			}
+		}
+		return nil
+	case 'm':
+		if index := u.selected(); index >= 0 {
+			u.markRead(index, !u.items[index].Read)
+		}
+		return nil
+	case 'x':
+		if index := u.selected(); index >= 0 {
+			u.showPluginActions(index)
+		}
+		return nil
+	case 'C':
+		if index := u.selected(); index >= 0 {
+			u.openComments(index)
+		}
+		return nil
+	case 's':
+		if index := u.selected(); index >= 0 {
+			u.toggleStarred(index)
+		}
+		return nil
+	}
+	return event
+}
+
+func (u *UI) handleFeedKey(event *tcell.EventKey) *tcell.EventKey {
+	if event.Key() == tcell.KeyTab {
+		u.app.SetFocus(u.table)
+		return nil
+	}
+
+	switch event.Rune() {
+	case 'q':
+		u.quit()
+		return nil
+	case 'L':
+		u.toggleLayout()
+		return nil
+	case 'l':
+		u.app.SetFocus(u.table)
+		return nil
+	case 'W':
+		u.openSite()
+		return nil
+	case 'Y':
+		u.copyFeedURL()
+		return nil
+	case 'p':
+		u.togglePinned()
+		return nil
+	case 'K':
+		u.moveFeed(-1)
+		return nil
+	case 'J':
+		u.moveFeed(1)
+		return nil
+	case 'r':
+		u.refreshSelected()
+		return nil
+	case 'R':
+		u.refresh()
+		return nil
+	}
+	return event
+}
+
+// toggleLayout switches between the merged timeline and the two-pane feed
+// list, keeping the selected item selected.
+func (u *UI) toggleLayout() {
+	selected := u.selected()
+	u.twoPane = !u.twoPane
+	u.feedFilter = feedEntry{}
+	u.layoutPanes()
+	if u.twoPane {
+		u.feeds.Select(0, 0)
+	}
+	u.render()
+	u.selectItem(selected)
+}
+
+// showProcesses opens a panel listing the players and other programs
+// newseum has started; x stops the selected one and Esc closes the panel.
+func (u *UI) showProcesses() {
+	list := tview.NewList().ShowSecondaryText(false)
+	list.SetBorder(true).SetTitle(" Running players (x to stop, Esc to close) ")
+	list.SetBackgroundColor(tcell.ColorDefault)
+
+	var running []*childProcess
+	refresh := func() {
+		list.Clear()
+		running = runningProcesses()
+		for _, p := range running {
+			list.AddItem(tview.Escape(p.String()), "", 0, nil)
+		}
+		if len(running) == 0 {
+			list.AddItem("No running processes", "", 0, nil)
+		}
+	}
+	refresh()
+
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape || event.Rune() == 'q':
+			u.pages.RemovePage("processes")
+			return nil
+		case event.Rune() == 'x':
+			i := list.GetCurrentItem()
+			if i < len(running) {
+				p := running[i]
+				if err := p.kill(); err == nil {
+					goSafe(func() {
+						<-p.done
+						u.app.QueueUpdateDraw(refresh)
+					})
+				}
+			}
+			return nil
+		}
+		return event
+	})
+
+	u.pages.AddPage("processes", centered(list, 100, 15), true, true)
+}
+
+// showEnclosures opens a chooser listing all of an item's enclosures;
+// Enter opens the selected one and d downloads it.
+func (u *UI) showEnclosures(index int) {
+	item := u.items[index]
+	if len(item.Enclosures) == 0 {
+		return
+	}
+
+	list := tview.NewList().ShowSecondaryText(true)
+	list.SetBorder(true).SetTitle(" Enclosures (Enter to open, d to download, Esc to close) ")
+	list.SetBackgroundColor(tcell.ColorDefault)
+	for _, enclosure := range item.Enclosures {
+		list.AddItem(tview.Escape(enclosureLabel(enclosure)), tview.Escape(stripControl(enclosure.URL)), 0, nil)
+	}
+
+	list.SetSelectedFunc(func(i int, _, _ string, _ rune) {
+		url := item.Enclosures[i].URL
+		if err := openURL(url); err != nil {
+			slog.Error("error opening enclosure", "url", url, "err", err)
+		}
+		u.markRead(index, true)
+		u.pages.RemovePage("enclosures")
+	})
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape || event.Rune() == 'q':
+			u.pages.RemovePage("enclosures")
+			return nil
+		case event.Rune() == 'd':
+			url := item.Enclosures[list.GetCurrentItem()].URL
+			goWrite(func() {
+				if _, err := downloadEnclosure(item, url); err != nil {
+					slog.Error("error downloading enclosure", "err", err)
+				}
+			})
+			u.pages.RemovePage("enclosures")
+			return nil
+		}
+		return event
+	})
+
+	u.pages.AddPage("enclosures", centered(list, 100, 2*len(item.Enclosures)+2), true, true)
+}
+
+// showImages opens a chooser listing the images in an item's description,
+// numbered as in the preview; Enter or the image's number shows it.
+func (u *UI) showImages(index int) {
+	item := u.items[index]
+	_, _, images := descriptionMarkup(item.Description.String(), parsedQuery{})
+	if len(images) == 0 {
+		u.setStatus("The item has no images")
+		return
+	}
+
+	list := tview.NewList().ShowSecondaryText(true)
+	list.SetBorder(true).SetTitle(" Images (Enter or number to view, Esc to close) ")
+	list.SetBackgroundColor(tcell.ColorDefault)
+	for i, image := range images {
+		shortcut := rune(0)
+		if i < 9 {
+			shortcut = rune('1' + i)
+		}
+		list.AddItem(tview.Escape(image.Text), tview.Escape(stripControl(image.URL)), shortcut, nil)
+	}
+
+	list.SetSelectedFunc(func(i int, _, _ string, _ rune) {
+		url := images[i].URL
+		if err := viewImage(url); err != nil {
+			slog.Error("error opening image", "url", url, "err", err)
+			u.setStatus("[red]Error opening " + tview.Escape(url))
+		}
+		u.pages.RemovePage("images")
+	})
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || event.Rune() == 'q' {
+			u.pages.RemovePage("images")
+			return nil
+		}
+		return event
+	})
+
+	u.pages.AddPage("images", centered(list, 100, min(2*len(images)+2, 24)), true, true)
+}
+
+// showLinks opens a picker with the links in an item's description. Enter
+// opens the selected link, y copies it, and a adds the links from the full
+// article, which happens automatically when the description has none.
+func (u *UI) showLinks(index int) {
+	item := u.items[index]
+	list := tview.NewList()
+	list.SetBorder(true).SetTitle(" Links (Enter to open, y to copy, a for article links, Esc to close) ")
+	list.SetBackgroundColor(tcell.ColorDefault)
+
+	var links []Link
+	addLinks := func(more []Link) {
+		seen := make(map[string]bool)
+		for _, link := range links {
+			seen[link.URL] = true
+		}
+		for _, link := range more {
+			if seen[link.URL] {
+				continue
+			}
+			seen[link.URL] = true
+			links = append(links, link)
+			text := link.Text
+			if text == "" {
+				text = link.URL
+			}
+			list.AddItem(tview.Escape(stripControl(text)), tview.Escape(stripControl(link.URL)), 0, nil)
+		}
+	}
+	articleLoaded := false
+	loadArticleLinks := func() {
+		if articleLoaded || item.Link == "" {
+			return
+		}
+		articleLoaded = true
+		list.SetTitle(" Links (loading article...) ")
+		goSafe(func() {
+			more, err := extractArticleLinks(item.Link)
+			if err != nil {
+				slog.Error("error loading article links", "err", err)
+			}
+			u.app.QueueUpdateDraw(func() {
+				addLinks(more)
+				list.SetTitle(" Links (Enter to open, y to copy, Esc to close) ")
+			})
+		})
+	}
+
+	addLinks(descriptionLinks(item.Description.String()))
+	if len(links) == 0 && !config.DataSaver {
+		loadArticleLinks()
+	}
+
+	list.SetSelectedFunc(func(i int, _, _ string, _ rune) {
+		if err := openURL(desktopLink(cleanLink(links[i].URL))); err != nil {
+			slog.Error("error opening link", "url", links[i].URL, "err", err)
+		}
+		u.pages.RemovePage("links")
+	})
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape || event.Rune() == 'q':
+			u.pages.RemovePage("links")
+			return nil
+		case event.Rune() == 'y':
+			if i := list.GetCurrentItem(); i < len(links) {
+				if err := copyToClipboard(desktopLink(cleanLink(links[i].URL))); err != nil {
+					slog.Error("error copying link", "err", err)
+				}
+				u.pages.RemovePage("links")
+			}
+			return nil
+		case event.Rune() == 'a':
+			loadArticleLinks()
+			return nil
+		}
+		return event
+	})
+
+	u.pages.AddPage("links", centered(list, 100, 20), true, true)
+}
+
+// centered returns a layout that shows p in the middle of the screen at the
+// given size, for use as an overlay page.
+func centered(p tview.Primitive, width, height int) tview.Primitive {
+	return tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(p, height, 1, true).
+			AddItem(nil, 0, 1, false), width, 1, true).
+		AddItem(nil, 0, 1, false)
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// version is set by release builds with -ldflags "-X main.version=v1.2.0".
+// go install of a tagged version gets it from the module instead.
+var version string
+
+// releasesURL is where the update check finds the latest release.
+const releasesURL = "https://api.github.com/repos/carterprince/newseum/releases/latest"
+
+// updateCheckInterval is how long the latest release found is trusted
+// before asking again.
+const updateCheckInterval = 24 * time.Hour
+
+// pseudoVersion matches the versions go build stamps on a checkout, like
+// v0.0.0-20260102150405-0123456789ab+dirty, which aren't releases.
+var pseudoVersion = regexp.MustCompile(`\d{14}-[0-9a-f]{12}(\+dirty)?$`)
+
+// currentVersion returns the release being run, or "devel" for a build
+// from a checkout.
+func currentVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" &&
+		!pseudoVersion.MatchString(info.Main.Version) {
+		return info.Main.Version
+	}
+	return "devel"
+}
+
+// buildSetting returns a setting recorded by go build, such as
+// vcs.revision, or "" if there is none.
+func buildSetting(key string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == key {
+			return setting.Value
+		}
+	}
+	return ""
+}
+
+// versionLine is what --version prints.
+func versionLine() string {
+	line := "newseum " + currentVersion()
+	details := []string{runtime.Version(), runtime.GOOS + "/" + runtime.GOARCH}
+	if commit := buildSetting("vcs.revision"); commit != "" {
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		if buildSetting("vcs.modified") == "true" {
+			commit += "+"
+		}
+		details = append([]string{commit}, details...)
+	}
+	return line + " (" + strings.Join(details, ", ") + ")"
+}
+
+// enabledFeatures lists the optional features the config turns on, for bug
+// reports.
+func enabledFeatures() []string {
+	var features []string
+	add := func(on bool, name string) {
+		if on {
+			features = append(features, name)
+		}
+	}
+	add(config.Backend != "", "backend "+config.Backend)
+	add(config.GPodderURL != "", "gpodder sync")
+	add(config.SyncFile != "", "sync-file")
+	add(config.WebListen != "", "web page")
+	add(config.MetricsListen != "", "metrics")
+	add(config.WebSubListen != "", "websub")
+	add(config.DNS != "", "custom dns")
+	add(config.PreferIP != "", "prefer ipv"+config.PreferIP)
+	add(config.Prefetch, "prefetch")
+	add(config.DataSaver, "data saver")
+	add(config.ArchiveStarred, "archive starred")
+	add(config.ScreenReader, "screen reader")
+	add(config.FuzzySearch, "fuzzy search")
+	add(config.TTSCommand != "", "tts")
+	add(config.TorrentClient != "", "torrent client")
+	add(config.UpdateCheck, "update check")
+	return features
+}
+
+// runBuildInfo prints the version, how newseum was built and what the
+// config enables, to paste into bug reports.
+func runBuildInfo() error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	config = cfg
+
+	fmt.Printf("version   %s\n", currentVersion())
+	if commit := buildSetting("vcs.revision"); commit != "" {
+		if buildSetting("vcs.modified") == "true" {
+			commit += " (modified)"
+		}
+		fmt.Printf("commit    %s\n", commit)
+	}
+	if built := buildSetting("vcs.time"); built != "" {
+		fmt.Printf("committed %s\n", built)
+	}
+	fmt.Printf("go        %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	if tags := buildSetting("-tags"); tags != "" {
+		fmt.Printf("tags      %s\n", tags)
+	}
+	if cgo := buildSetting("CGO_ENABLED"); cgo != "" {
+		fmt.Printf("cgo       %s\n", cgo)
+	}
+	features := enabledFeatures()
+	if len(features) == 0 {
+		features = []string{"none"}
+	}
+	fmt.Printf("features  %s\n", strings.Join(features, ", "))
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			fmt.Printf("dep       %s %s\n", dep.Path, dep.Version)
+		}
+	}
+
+	if config.UpdateCheck {
+		if latest, err := latestRelease(); err != nil {
+			fmt.Printf("latest    unknown: %v\n", err)
+		} else {
+			fmt.Printf("latest    %s\n", latest)
+		}
+	}
+	return nil
+}
+
+// updateStamp is the latest release found, saved so it's asked for at most
+// once a day.
+type updateStamp struct {
+	Checked time.Time `json:"checked"`
+	Latest  string    `json:"latest"`
+}
+
+func updateStampPath() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "update.json"), nil
+}
+
+// latestRelease returns the tag of the latest release, asking the releases
+// page unless it was asked within updateCheckInterval.
+func latestRelease() (string, error) {
+	path, err := updateStampPath()
+	if err != nil {
+		return "", err
+	}
+	var stamp updateStamp
+	if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &stamp) == nil &&
+		stamp.Latest != "" && time.Since(stamp.Checked) < updateCheckInterval {
+		return stamp.Latest, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", releasesURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "newseum")
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("http error: %s", resp.Status)
+	}
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", fmt.Errorf("error reading release: %v", err)
+	}
+	if release.TagName == "" {
+		return "", fmt.Errorf("no release found")
+	}
+
+	stamp = updateStamp{Checked: time.Now().UTC(), Latest: release.TagName}
+	if data, err := json.Marshal(stamp); err == nil {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+			tmp := path + ".tmp"
+			if err := os.WriteFile(tmp, data, 0o644); err == nil {
+				os.Rename(tmp, path)
+			}
+		}
+	}
+	return release.TagName, nil
+}
+
+// newerVersion reports whether release is a later version than current.
+// A devel build is never reported as out of date.
+func newerVersion(release, current string) bool {
+	a, ok := versionNumbers(release)
+	if !ok {
+		return false
+	}
+	b, ok := versionNumbers(current)
+	if !ok {
+		return false
+	}
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return x > y
+		}
+	}
+	return false
+}
+
+// versionNumbers parses "v1.2.3" into its numbers, ignoring any
+// pre-release or build suffix.
+func versionNumbers(v string) ([]int, bool) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	var numbers []int
+	for _, part := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, true
+}
+
+// checkForUpdate tells in the status bar when update-check is on and a
+// newer release is out.
+func (u *UI) checkForUpdate() {
+	if !config.UpdateCheck || config.DataSaver {
+		return
+	}
+	goSafe(func() {
+		latest, err := latestRelease()
+		if err != nil {
+			slog.Info("update check failed", "err", err)
+			return
+		}
+		if !newerVersion(latest, currentVersion()) {
+			return
+		}
+		u.app.QueueUpdateDraw(func() {
+			u.newRelease = latest
+			// Otherwise it follows the refresh's summary
+			if u.cancelRefresh == nil {
+				u.setStatus(u.releaseNotice(""))
+			}
+		})
+	})
+}
+
+// releaseNotice returns, once, the news of a newer release after sep.
+func (u *UI) releaseNotice(sep string) string {
+	if u.newRelease == "" {
+		return ""
+	}
+	notice := sep + "newseum " + u.newRelease + " is out (this is " + currentVersion() + ")"
+	u.newRelease = ""
+	return notice
+}
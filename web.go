@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// webPageItems is the most items the web page lists.
+const webPageItems = 300
+
+// webPage is the daemon's read-only timeline for browsers. Opening an item
+// there marks it read in the state file the interface shares.
+var webPage = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>newseum</title>
+<link rel="alternate" type="application/atom+xml" title="newseum" href="/feed.atom{{if .Unread}}?unread=1{{end}}">
+<style>
+body { font: 16px/1.4 system-ui, sans-serif; margin: 0 auto; max-width: 48rem; padding: 0 1rem; }
+@media (prefers-color-scheme: dark) { body { background: #111; color: #ddd; } a { color: #8ab4f8; } }
+nav { display: flex; gap: 1rem; padding: 1rem 0; border-bottom: 1px solid #8884; }
+ul { list-style: none; padding: 0; margin: 0; }
+li { display: flex; gap: .5rem; align-items: baseline; padding: .6rem 0; border-bottom: 1px solid #8882; }
+li div { flex: 1; min-width: 0; }
+li a { text-decoration: none; font-weight: 600; overflow-wrap: anywhere; }
+li.read a { font-weight: normal; opacity: .6; }
+small { display: block; opacity: .7; }
+button { font: inherit; background: none; border: 1px solid #8886; border-radius: .3rem; color: inherit; }
+</style>
+</head>
+<body>
+<nav>
+<strong>newseum</strong>
+{{if .Unread}}<a href="/">All</a> · Unread ({{len .Items}}){{else}}All · <a href="/?unread=1">Unread</a>{{end}}
+</nav>
+<ul>
+{{range .Items}}
+<li{{if .Read}} class="read"{{end}}>
+<div>
+<a href="/open?key={{.Key}}" target="_blank" rel="noopener">{{.Title}}</a>
+<small>{{.Feed}} · {{.Date}}</small>
+</div>
+<form method="post" action="/read">
+<input type="hidden" name="key" value="{{.Key}}">
+{{if $.Unread}}<input type="hidden" name="unread" value="1">{{end}}
+{{if .Read}}<button name="read" value="false">Unread</button>{{else}}<button name="read" value="true">Read</button>{{end}}
+</form>
+</li>
+{{else}}
+<li>No items yet.</li>
+{{end}}
+</ul>
+</body>
+</html>
+`))
+
+// webItem is an item as the page shows it.
+type webItem struct {
+	Key   string
+	Title string
+	Feed  string
+	Date  string
+	Read  bool
+}
+
+// startWebUI serves the web page on web-listen until ctx is done.
+func startWebUI(ctx context.Context) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", serveWebPage)
+	mux.HandleFunc("GET /open", serveWebOpen)
+	mux.HandleFunc("POST /read", serveWebRead)
+	mux.HandleFunc("GET /feed.atom", serveAtomFeed)
+	server := &http.Server{Addr: config.WebListen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	goSafe(func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("error serving web page", "addr", config.WebListen, "err", err)
+		}
+	})
+	goSafe(func() {
+		<-ctx.Done()
+		server.Close()
+	})
+}
+
+// savedItems returns the cached items, newest first, with the read state
+// currently saved.
+func savedItems() ([]FeedItem, *readState, error) {
+	var items []FeedItem
+	for _, saved := range loadItemCache() {
+		items = append(items, saved...)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Date.After(items[j].Date)
+	})
+	state, err := loadReadState()
+	if err != nil {
+		return nil, nil, err
+	}
+	state.apply(items)
+	return items, state, nil
+}
+
+func serveWebPage(w http.ResponseWriter, r *http.Request) {
+	items, _, err := savedItems()
+	if err != nil {
+		slog.Error("error loading read state", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	unread := r.URL.Query().Get("unread") != ""
+	now := time.Now()
+	var shown []webItem
+	for _, item := range items {
+		if unread && item.Read {
+			continue
+		}
+		shown = append(shown, webItem{
+			Key:   itemKey(item),
+			Title: CleanString(item.Title),
+			Feed:  CleanString(item.FeedTitle),
+			Date:  formatDate(item.Date, now),
+			Read:  item.Read,
+		})
+		if len(shown) == webPageItems {
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = webPage.Execute(w, struct {
+		Items  []webItem
+		Unread bool
+	}{shown, unread})
+	if err != nil {
+		slog.Error("error writing web page", "err", err)
+	}
+}
+
+// serveWebOpen marks an item read and sends the browser on to its link.
+func serveWebOpen(w http.ResponseWriter, r *http.Request) {
+	item, state, ok := findWebItem(w, r.URL.Query().Get("key"))
+	if !ok {
+		return
+	}
+	if !item.Read {
+		item.Read = true
+		if err := state.update(item); err != nil {
+			slog.Error("error saving state file", "err", err)
+		}
+	}
+	if item.Link == "" {
+		http.Error(w, "This item has no link", http.StatusNotFound)
+		return
+	}
+	if err := recordOpen(item); err != nil {
+		slog.Warn("error saving history", "err", err)
+	}
+	http.Redirect(w, r, desktopLink(item.Link), http.StatusSeeOther)
+}
+
+// serveWebRead marks an item read or unread and goes back to the page.
+func serveWebRead(w http.ResponseWriter, r *http.Request) {
+	if !sameOrigin(r) {
+		http.Error(w, "Cross-origin request refused", http.StatusForbidden)
+		return
+	}
+	item, state, ok := findWebItem(w, r.FormValue("key"))
+	if !ok {
+		return
+	}
+	item.Read = r.FormValue("read") == "true"
+	if err := state.update(item); err != nil {
+		slog.Error("error saving state file", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	back := "/"
+	if r.FormValue("unread") != "" {
+		back = "/?unread=1"
+	}
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// sameOrigin reports whether a form was posted from the page itself rather
+// than from another site the browser has open, going by the Sec-Fetch-Site
+// and Origin headers browsers send.
+func sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+	default:
+		return false
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host != r.Host {
+			return false
+		}
+	}
+	return true
+}
+
+// findWebItem looks up a cached item by its key, answering with an error
+// if it isn't there.
+func findWebItem(w http.ResponseWriter, key string) (FeedItem, *readState, bool) {
+	items, state, err := savedItems()
+	if err != nil {
+		slog.Error("error loading read state", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return FeedItem{}, nil, false
+	}
+	for _, item := range items {
+		if itemKey(item) == key {
+			return item, state, true
+		}
+	}
+	http.Error(w, "No such item", http.StatusNotFound)
+	return FeedItem{}, nil, false
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+const (
+	// webSubLease is the subscription length asked of hubs, which may
+	// grant another.
+	webSubLease = 7 * 24 * time.Hour
+	// webSubRecheck is how long until feeds without a hub, or whose hub
+	// didn't confirm the subscription, are tried again.
+	webSubRecheck = 24 * time.Hour
+	// webSubMaxBody limits the size of pushed content.
+	webSubMaxBody = 16 << 20
+)
+
+// webSubscription is a feed the daemon has asked its hub to push.
+type webSubscription struct {
+	source FeedSource
+	hub    string
+	// topic is the feed's URL as the hub knows it, which may differ from
+	// the one in feeds.csv.
+	topic  string
+	secret string
+	// checked is when the hub was last looked for and asked to subscribe.
+	checked time.Time
+	// expires is when the lease the hub confirmed ends; zero until then.
+	expires time.Time
+	// unsubscribing is set while newseum waits for the hub to confirm an
+	// unsubscribe it asked for.
+	unsubscribing bool
+}
+
+// webSub receives content pushed by WebSub (PubSubHubbub) hubs, which
+// deliver a feed's new entries as soon as they're published.
+type webSub struct {
+	mutex sync.Mutex
+	// subs are keyed by the id in their callback URL. Only feeds with a
+	// hub have one.
+	subs map[string]*webSubscription
+	// noHub is when each feed found without a hub was last looked at, by
+	// the same id.
+	noHub  map[string]time.Time
+	onPush func(FeedSource, []FeedItem)
+}
+
+// startWebSub listens on websub-listen for hubs' verifications and pushes,
+// calling onPush with each feed's pushed items, until ctx is done.
+func startWebSub(ctx context.Context, onPush func(FeedSource, []FeedItem)) (*webSub, error) {
+	if config.WebSubCallback == "" {
+		return nil, fmt.Errorf("websub-callback must be set along with websub-listen")
+	}
+	w := &webSub{subs: make(map[string]*webSubscription), noHub: make(map[string]time.Time), onPush: onPush}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /websub/{id}", w.verify)
+	mux.HandleFunc("POST /websub/{id}", w.receive)
+	server := &http.Server{Addr: config.WebSubListen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	goSafe(func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("error serving websub callbacks", "addr", config.WebSubListen, "err", err)
+		}
+	})
+	goSafe(func() {
+		<-ctx.Done()
+		server.Close()
+	})
+	return w, nil
+}
+
+// webSubID names a feed's callback, so a push can't be passed off as
+// another feed's.
+func webSubID(feedURL string) string {
+	sum := sha256.Sum256([]byte(feedURL))
+	return hex.EncodeToString(sum[:8])
+}
+
+// subscribeAll asks the hubs of the feeds that advertise one to push them,
+// renewing leases that are about to end.
+func (w *webSub) subscribeAll(ctx context.Context, sources []FeedSource) {
+	now := time.Now()
+	for _, source := range sources {
+		if !source.webFeed() {
+			continue
+		}
+		id := webSubID(source.URL)
+		w.mutex.Lock()
+		sub := w.subs[id]
+		checked, looked := w.noHub[id]
+		due := sub == nil && (!looked || now.Sub(checked) > webSubRecheck) ||
+			sub != nil && !sub.expires.IsZero() && sub.expires.Sub(now) < time.Hour ||
+			sub != nil && sub.expires.IsZero() && now.Sub(sub.checked) > webSubRecheck
+		w.mutex.Unlock()
+		if !due {
+			continue
+		}
+
+		hub, topic, err := discoverHub(ctx, source)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Warn("error looking for websub hub", "url", source.URL, "err", err)
+		}
+		if hub == "" {
+			w.mutex.Lock()
+			delete(w.subs, id)
+			w.noHub[id] = now
+			w.mutex.Unlock()
+			continue
+		}
+		secret, err := newWebSubSecret()
+		if err != nil {
+			slog.Error("error generating websub secret", "err", err)
+			return
+		}
+		sub = &webSubscription{source: source, hub: hub, topic: topic, secret: secret, checked: now}
+		w.mutex.Lock()
+		delete(w.noHub, id)
+		w.subs[id] = sub
+		w.mutex.Unlock()
+		if err := w.request(ctx, id, sub, "subscribe"); err != nil {
+			slog.Warn("error subscribing to websub hub", "url", source.URL, "hub", hub, "err", err)
+		}
+	}
+}
+
+// request asks a hub to subscribe or unsubscribe. The hub confirms by
+// calling verify.
+func (w *webSub) request(ctx context.Context, id string, sub *webSubscription, mode string) error {
+	form := url.Values{
+		"hub.mode":          {mode},
+		"hub.topic":         {sub.topic},
+		"hub.callback":      {strings.TrimSuffix(config.WebSubCallback, "/") + "/websub/" + id},
+		"hub.lease_seconds": {strconv.Itoa(int(webSubLease / time.Second))},
+		"hub.secret":        {sub.secret},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", sub.hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "newseum")
+	if mode == "unsubscribe" {
+		w.mutex.Lock()
+		sub.unsubscribing = true
+		w.mutex.Unlock()
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("http error: %s %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// verify answers a hub checking that a subscription was asked for, by
+// echoing its challenge.
+func (w *webSub) verify(rw http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	w.mutex.Lock()
+	sub := w.subs[r.PathValue("id")]
+	w.mutex.Unlock()
+	if sub == nil || query.Get("hub.topic") != sub.topic {
+		http.NotFound(rw, r)
+		return
+	}
+
+	switch query.Get("hub.mode") {
+	case "subscribe":
+		lease, err := strconv.Atoi(query.Get("hub.lease_seconds"))
+		if err != nil || lease <= 0 {
+			lease = int(webSubLease / time.Second)
+		}
+		w.mutex.Lock()
+		sub.expires = time.Now().Add(time.Duration(lease) * time.Second)
+		w.mutex.Unlock()
+		slog.Info("websub subscription confirmed", "url", sub.source.URL, "hub", sub.hub, "lease", time.Duration(lease)*time.Second)
+	case "unsubscribe":
+		w.mutex.Lock()
+		asked := sub.unsubscribing
+		if asked {
+			delete(w.subs, r.PathValue("id"))
+		}
+		w.mutex.Unlock()
+		if !asked {
+			http.NotFound(rw, r)
+			return
+		}
+		slog.Info("websub unsubscribe confirmed", "url", sub.source.URL, "hub", sub.hub)
+	case "denied":
+		slog.Warn("websub hub denied subscription", "url", sub.source.URL, "hub", sub.hub, "reason", query.Get("hub.reason"))
+		w.mutex.Lock()
+		sub.expires = time.Time{}
+		w.mutex.Unlock()
+		return
+	default:
+		http.Error(rw, "unknown hub.mode", http.StatusBadRequest)
+		return
+	}
+	io.WriteString(rw, query.Get("hub.challenge"))
+}
+
+// receive takes the content a hub pushes to a confirmed subscription. It
+// is acknowledged even when the signature is missing or doesn't match, as
+// WebSub asks, but then ignored.
+func (w *webSub) receive(rw http.ResponseWriter, r *http.Request) {
+	w.mutex.Lock()
+	sub := w.subs[r.PathValue("id")]
+	confirmed := sub != nil && time.Now().Before(sub.expires)
+	w.mutex.Unlock()
+	if !confirmed {
+		http.NotFound(rw, r)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, webSubMaxBody))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rw.WriteHeader(http.StatusAccepted)
+
+	if !validSignature(r.Header.Get("X-Hub-Signature"), sub.secret, body) {
+		slog.Warn("ignoring websub push with a bad signature", "url", sub.source.URL)
+		return
+	}
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(body))
+	if err != nil {
+		slog.Warn("error parsing websub push", "url", sub.source.URL, "err", err)
+		return
+	}
+	source := sub.source
+	if source.Name == "" {
+		source.Name = resolveFeedTitle(source.URL, feed.Title)
+	}
+	w.onPush(source, feedItems(source, feed))
+}
+
+// validSignature checks the X-Hub-Signature header, "method=hex", of a push
+// against the subscription's secret. A push without one is never valid.
+func validSignature(header, secret string, body []byte) bool {
+	if secret == "" || header == "" {
+		return false
+	}
+	method, signature, ok := strings.Cut(header, "=")
+	if !ok {
+		return false
+	}
+	var newHash func() hash.Hash
+	switch method {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+	want, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+func newWebSubSecret() (string, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// discoverHub looks for the hub a feed advertises, in its Link headers or
+// its atom:link elements, and the topic URL it names itself by. It returns
+// an empty hub for feeds without one.
+func discoverHub(ctx context.Context, source FeedSource) (hub, topic string, err error) {
+	topic = source.URL
+	client, err := feedClient(source.TLS)
+	if err != nil {
+		return "", topic, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", source.URL, nil)
+	if err != nil {
+		return "", topic, err
+	}
+	req.Header.Set("User-Agent", "newseum")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", topic, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", topic, fmt.Errorf("http error: %s", resp.Status)
+	}
+
+	self := ""
+	for _, header := range resp.Header.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			href, rel := parseLinkHeader(link)
+			switch {
+			case hasRel(rel, "hub") && hub == "":
+				hub = href
+			case hasRel(rel, "self") && self == "":
+				self = href
+			}
+		}
+	}
+
+	// The links come before the first item, so the rest isn't read
+	decoder := xml.NewDecoder(io.LimitReader(resp.Body, webSubMaxBody))
+	decoder.Strict = false
+	for hub == "" || self == "" {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local == "item" || start.Name.Local == "entry" {
+			break
+		}
+		if start.Name.Local != "link" {
+			continue
+		}
+		var href, rel string
+		for _, attr := range start.Attr {
+			switch attr.Name.Local {
+			case "href":
+				href = attr.Value
+			case "rel":
+				rel = attr.Value
+			}
+		}
+		switch {
+		case hasRel(rel, "hub") && hub == "":
+			hub = href
+		case hasRel(rel, "self") && self == "":
+			self = href
+		}
+	}
+
+	if hub != "" {
+		if base, err := url.Parse(source.URL); err == nil {
+			if u, err := base.Parse(hub); err == nil {
+				hub = u.String()
+			}
+			if u, err := base.Parse(self); err == nil && self != "" {
+				topic = u.String()
+			}
+		}
+	}
+	return hub, topic, nil
+}
+
+// parseLinkHeader splits one link of a Link header, `<url>; rel="hub"`.
+func parseLinkHeader(link string) (href, rel string) {
+	parts := strings.Split(link, ";")
+	href = strings.Trim(strings.TrimSpace(parts[0]), "<>")
+	for _, param := range parts[1:] {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if ok && strings.EqualFold(key, "rel") {
+			rel = strings.Trim(value, `"`)
+		}
+	}
+	return href, rel
+}
+
+// hasRel reports whether a space-separated rel attribute includes name.
+func hasRel(rel, name string) bool {
+	for _, r := range strings.Fields(rel) {
+		if strings.EqualFold(r, name) {
+			return true
+		}
+	}
+	return false
+}